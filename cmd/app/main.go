@@ -1,9 +1,23 @@
 package main
 
-import "avito-test2024-spring/internal/app"
+import (
+	"avito-test2024-spring/internal/app"
+	"os"
+)
 
 const ConfigPath = "../../configs/main"
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "create-admin":
+			app.CreateAdmin(ConfigPath)
+			return
+		case "migrate":
+			app.Migrate(ConfigPath, os.Args[2:])
+			return
+		}
+	}
+
 	app.Run(ConfigPath)
 }
@@ -9,10 +9,16 @@ import (
 	"avito-test2024-spring/pkg/auth"
 	cache2 "avito-test2024-spring/pkg/cache"
 	"avito-test2024-spring/pkg/database/postgresql"
+	"avito-test2024-spring/pkg/database/postgresql/migrations"
 	"avito-test2024-spring/pkg/logger"
+	"avito-test2024-spring/pkg/pubsub"
+	"context"
+	"encoding/json"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 )
@@ -36,14 +42,43 @@ func Run(configPath string) {
 	logs.Logger.Info().Msg("Starting app")
 	logs.Logger.Info().Interface("config", cfg).Msg("")
 
-	cache := cache2.NewRedisCache(cfg.Cache)
-	logs.Logger.Info().Msg("Initialized connection pool Cache")
+	cache, err := cache2.NewCache(cfg.Cache, cfg.Redis)
+	if err != nil {
+		log.Fatal(err.Error())
+		return
+	}
+	logs.Logger.Info().Str("backend", cfg.Cache.Backend).Msg("Initialized Cache")
+
+	events, err := pubsub.NewPubSub(cfg.Events, cfg.Redis)
+	if err != nil {
+		log.Fatal(err.Error())
+		return
+	}
+	logs.Logger.Info().Str("backend", cfg.Events.Backend).Msg("Initialized banner events")
 
 	time.Sleep(15 * time.Second)
 
 	dbPool := postgresql.NewConnectionPool(cfg.PostgreSQL, logs)
+	if dbPool == nil {
+		log.Fatal("error while connecting to DB")
+		return
+	}
 	logs.Logger.Info().Msg("Initialized connection pool DB")
 
+	migrator, err := migrations.NewMigrator(dbPool)
+	if err != nil {
+		log.Fatal(err.Error())
+		return
+	}
+
+	applied, err := migrator.Up(context.Background())
+	if err != nil {
+		logs.Logger.Error().Err(err).Msg("error occurred while migrating DB schema")
+		log.Fatal(err.Error())
+		return
+	}
+	logs.Logger.Info().Int("applied", applied).Int("version", migrator.Latest()).Msg("Migrated DB schema")
+
 	repos := repository.NewRepositories(dbPool)
 	logs.Logger.Info().Msg("Initialized repos")
 
@@ -53,10 +88,24 @@ func Run(configPath string) {
 	}
 	logs.Logger.Info().Msg("Initialized tokenManager")
 
-	services := service.NewServices(repos, tokenManager, cache)
+	services := service.NewServices(repos, tokenManager, cache, events, logs.Logger, cfg)
 	logs.Logger.Info().Msg("Initialized services")
 
-	handlers := controller.NewHandler(services.Banners, services.Tags, services.Features, services.Users, logs, tokenManager, cache)
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	services.Jobs.Run(jobsCtx)
+	logs.Logger.Info().Msg("Started jobs workers")
+
+	services.Banners.RunScheduler(jobsCtx)
+	logs.Logger.Info().Msg("Started banners scheduler")
+
+	services.Stats.Run(jobsCtx)
+	logs.Logger.Info().Msg("Started stats flusher")
+
+	events.Run(jobsCtx)
+	logs.Logger.Info().Msg("Started banner events receiver")
+
+	handlers := controller.NewHandler(services.Banners, services.Tags, services.Features, services.Users, services.Jobs,
+		services.Stats, logs, tokenManager, cache)
 	logs.Logger.Info().Msg("Initialized handlers")
 
 	srv := server.NewServer(cfg.HTTP, handlers.Init("localhost", cfg.HTTP.Port))
@@ -73,7 +122,137 @@ func Run(configPath string) {
 
 	<-quit
 
+	stopJobs()
+
+	if err := services.Stats.Flush(context.Background()); err != nil {
+		logs.Logger.Error().Err(err).Msg("error occurred while flushing banners stats")
+	}
+
 	dbPool.Close()
 
 	logs.Logger.Info().Msg("End of app")
 }
+
+// CreateAdmin creates a new admin user and prints the tokens of the created admin. It is used to get the first admin,
+// because users API is available only for admins.
+func CreateAdmin(configPath string) {
+	cfg, err := config.Init(configPath)
+	if err != nil {
+		log.Fatal(err.Error())
+		return
+	}
+
+	logs := logger.NewLogs(cfg.Logger)
+
+	dbPool := postgresql.NewConnectionPool(cfg.PostgreSQL, logs)
+	if dbPool == nil {
+		log.Fatal("error while connecting to DB")
+		return
+	}
+	defer dbPool.Close()
+
+	migrator, err := migrations.NewMigrator(dbPool)
+	if err != nil {
+		log.Fatal(err.Error())
+		return
+	}
+
+	if err := migrator.Check(context.Background()); err != nil {
+		log.Fatal(err.Error())
+		return
+	}
+
+	tokenManager, err := auth.NewManager(cfg.JWT.SigningKey)
+	if err != nil {
+		log.Fatal(err.Error())
+		return
+	}
+
+	cache, err := cache2.NewCache(cfg.Cache, cfg.Redis)
+	if err != nil {
+		log.Fatal(err.Error())
+		return
+	}
+
+	repos := repository.NewRepositories(dbPool)
+	// no events are published while creating admin, so they are not sent to other replicas
+	services := service.NewServices(repos, tokenManager, cache, pubsub.NewHub(cfg.Events.BufferSize),
+		logs.Logger, cfg)
+
+	tokens, errResp := services.Users.AddUser(context.Background(), service.UserAddInput{IsAdmin: true})
+	if errResp != nil {
+		log.Fatal(errResp.Error())
+		return
+	}
+
+	if err := json.NewEncoder(os.Stdout).Encode(tokens); err != nil {
+		log.Fatal(err.Error())
+	}
+}
+
+// Migrate manages DB schema. Supported commands: "up" applies all pending migrations,
+// "down [steps]" rolls back the given number of migrations (1 by default), "version" prints current version.
+func Migrate(configPath string, args []string) {
+	cfg, err := config.Init(configPath)
+	if err != nil {
+		log.Fatal(err.Error())
+		return
+	}
+
+	logs := logger.NewLogs(cfg.Logger)
+
+	dbPool := postgresql.NewConnectionPool(cfg.PostgreSQL, logs)
+	if dbPool == nil {
+		log.Fatal("error while connecting to DB")
+		return
+	}
+	defer dbPool.Close()
+
+	migrator, err := migrations.NewMigrator(dbPool)
+	if err != nil {
+		log.Fatal(err.Error())
+		return
+	}
+
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	ctx := context.Background()
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			log.Fatal(err.Error())
+			return
+		}
+		fmt.Printf("applied %v migrations\n", applied)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps <= 0 {
+				log.Fatal("steps must be a positive number")
+				return
+			}
+		}
+
+		rolledBack, err := migrator.Down(ctx, steps)
+		if err != nil {
+			log.Fatal(err.Error())
+			return
+		}
+		fmt.Printf("rolled back %v migrations\n", rolledBack)
+	case "version":
+		version, err := migrator.Version(ctx)
+		if err != nil {
+			log.Fatal(err.Error())
+			return
+		}
+		fmt.Printf("DB version: %v, latest version: %v\n", version, migrator.Latest())
+	default:
+		log.Fatalf("unknown migrate command %q, use up, down [steps] or version", command)
+	}
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"github.com/spf13/viper"
 	filepath2 "path/filepath"
 	"runtime"
@@ -11,6 +12,37 @@ const (
 	defaultHttpPort    = "8080"
 	defaultRWTimeout   = 10 * time.Second
 	defaultLoggerLevel = 5
+
+	minBannerVersionsLimit    = 3
+	defaultBannersCacheUpdate = "evict"
+	defaultSchedulerInterval  = 10 * time.Second
+	defaultVariantsCacheTTL   = 30 * time.Second
+
+	defaultAccessTokenTTL  = 15 * time.Minute
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
+	defaultSessionCheck    = "cache"
+	defaultSessionCacheTTL = 10 * time.Second
+
+	defaultUserTagsCacheTTL = 10 * time.Second
+
+	defaultCacheBackend = "redis"
+	defaultCacheSize    = 10000
+	defaultCacheTTL     = 5 * time.Second
+
+	defaultJobsWorkers      = 1
+	defaultJobsQueueSize    = 100
+	defaultJobsBatchSize    = 1000
+	defaultJobsPollInterval = 30 * time.Second
+	defaultJobsLeaseTimeout = 5 * time.Minute
+
+	defaultStatsFlushInterval = 5 * time.Second
+
+	defaultEventsBackend    = "redis"
+	defaultEventsChannel    = "banners:events"
+	defaultEventsBufferSize = 16
+
+	defaultPageLimit = 100
+	defaultMaxLimit  = 1000
 )
 
 type Config struct {
@@ -18,7 +50,14 @@ type Config struct {
 	HTTP       HTTPConfig
 	Logger     LoggerConfig
 	JWT        JWTConfig
-	Cache      RedisConfig
+	Redis      RedisConfig
+	Cache      CacheConfig
+	Banners    BannersConfig
+	Jobs       JobsConfig
+	Stats      StatsConfig
+	Pagination PaginationConfig
+	Events     EventsConfig
+	Users      UsersConfig
 }
 
 type LoggerConfig struct {
@@ -49,7 +88,12 @@ type PostgreSQLConfig struct {
 }
 
 type JWTConfig struct {
-	SigningKey string
+	SigningKey      string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	// SessionCheck is one of "none", "db" or "cache" and sets how revoked sessions are detected
+	SessionCheck    string
+	SessionCacheTTL time.Duration
 }
 
 type RedisConfig struct {
@@ -61,6 +105,61 @@ type RedisConfig struct {
 	MaxNumberOfRetries int
 }
 
+type CacheConfig struct {
+	// Backend is one of "redis", "memory" or "tiered" (memory in front of redis)
+	Backend string
+	Size    int
+	TTL     time.Duration
+}
+
+type BannersConfig struct {
+	VersionsLimit int
+	// CacheUpdate is one of "evict" or "write_through" and sets how cached banner is refreshed after update
+	CacheUpdate string
+	// EarlyRefreshBeta enables probabilistic refresh of cached user banner before it expires, 0 disables it.
+	// The greater the value, the earlier entries are refreshed.
+	EarlyRefreshBeta float64
+	// SchedulerInterval sets how often cache is cleaned from banners whose activation window starts or ends
+	SchedulerInterval time.Duration
+	// VariantsCacheTTL sets how long variants of A/B experiment are kept in memory of the instance
+	VariantsCacheTTL time.Duration
+}
+
+type JobsConfig struct {
+	Workers      int
+	QueueSize    int
+	BatchSize    int
+	PollInterval time.Duration
+	// LeaseTimeout is how long a running job may stay not updated before another worker takes it
+	LeaseTimeout time.Duration
+}
+
+// PaginationConfig sets page size of admin lists: DefaultLimit is used when limit is not set,
+// greater limits are reduced to MaxLimit.
+type PaginationConfig struct {
+	DefaultLimit int
+	MaxLimit     int
+}
+
+type StatsConfig struct {
+	// FlushInterval sets how often impressions buffered in memory are written to DB
+	FlushInterval time.Duration
+}
+
+type EventsConfig struct {
+	// Backend is one of "redis" (events reach all replicas) or "memory" (only subscribers of the same instance)
+	Backend string
+	// Channel is the redis channel events are published to
+	Channel string
+	// BufferSize is the number of events kept for a slow subscriber, further events are dropped for it
+	BufferSize int
+}
+
+type UsersConfig struct {
+	// TagsCacheTTL sets how long tags of a user are kept in memory of the instance for choosing user banners
+	TagsCacheTTL time.Duration
+}
+
 func Init(path string) (*Config, error) {
 	// setDefault()
 
@@ -108,9 +207,149 @@ func unmarshal(cfg *Config) error {
 		return err
 	}
 
-	if err := viper.UnmarshalKey("redis", &cfg.Cache); err != nil {
+	if cfg.JWT.AccessTokenTTL <= 0 {
+		cfg.JWT.AccessTokenTTL = defaultAccessTokenTTL
+	}
+
+	if cfg.JWT.RefreshTokenTTL <= 0 {
+		cfg.JWT.RefreshTokenTTL = defaultRefreshTokenTTL
+	}
+
+	if cfg.JWT.SessionCheck == "" {
+		cfg.JWT.SessionCheck = defaultSessionCheck
+	}
+
+	switch cfg.JWT.SessionCheck {
+	case "none", "db", "cache":
+	default:
+		return fmt.Errorf("unknown jwt.sessionCheck %q, expected none, db or cache", cfg.JWT.SessionCheck)
+	}
+
+	if cfg.JWT.SessionCacheTTL <= 0 {
+		cfg.JWT.SessionCacheTTL = defaultSessionCacheTTL
+	}
+
+	if err := viper.UnmarshalKey("users", &cfg.Users); err != nil {
+		return err
+	}
+
+	if cfg.Users.TagsCacheTTL <= 0 {
+		cfg.Users.TagsCacheTTL = defaultUserTagsCacheTTL
+	}
+
+	if err := viper.UnmarshalKey("redis", &cfg.Redis); err != nil {
+		return err
+	}
+
+	if err := viper.UnmarshalKey("cache", &cfg.Cache); err != nil {
+		return err
+	}
+
+	if cfg.Cache.Backend == "" {
+		cfg.Cache.Backend = defaultCacheBackend
+	}
+
+	if cfg.Cache.Size <= 0 {
+		cfg.Cache.Size = defaultCacheSize
+	}
+
+	if cfg.Cache.TTL <= 0 {
+		cfg.Cache.TTL = defaultCacheTTL
+	}
+
+	if err := viper.UnmarshalKey("banners", &cfg.Banners); err != nil {
 		return err
 	}
 
+	if cfg.Banners.VersionsLimit < minBannerVersionsLimit {
+		cfg.Banners.VersionsLimit = minBannerVersionsLimit
+	}
+
+	if cfg.Banners.CacheUpdate == "" {
+		cfg.Banners.CacheUpdate = defaultBannersCacheUpdate
+	}
+
+	switch cfg.Banners.CacheUpdate {
+	case "evict", "write_through":
+	default:
+		return fmt.Errorf("unknown banners.cacheUpdate %q, expected evict or write_through", cfg.Banners.CacheUpdate)
+	}
+
+	if cfg.Banners.EarlyRefreshBeta < 0 {
+		cfg.Banners.EarlyRefreshBeta = 0
+	}
+
+	if cfg.Banners.SchedulerInterval <= 0 {
+		cfg.Banners.SchedulerInterval = defaultSchedulerInterval
+	}
+
+	if cfg.Banners.VariantsCacheTTL <= 0 {
+		cfg.Banners.VariantsCacheTTL = defaultVariantsCacheTTL
+	}
+
+	if err := viper.UnmarshalKey("jobs", &cfg.Jobs); err != nil {
+		return err
+	}
+
+	if cfg.Jobs.Workers <= 0 {
+		cfg.Jobs.Workers = defaultJobsWorkers
+	}
+
+	if cfg.Jobs.QueueSize <= 0 {
+		cfg.Jobs.QueueSize = defaultJobsQueueSize
+	}
+
+	if cfg.Jobs.BatchSize <= 0 {
+		cfg.Jobs.BatchSize = defaultJobsBatchSize
+	}
+
+	if cfg.Jobs.PollInterval <= 0 {
+		cfg.Jobs.PollInterval = defaultJobsPollInterval
+	}
+
+	if cfg.Jobs.LeaseTimeout <= 0 {
+		cfg.Jobs.LeaseTimeout = defaultJobsLeaseTimeout
+	}
+
+	if err := viper.UnmarshalKey("stats", &cfg.Stats); err != nil {
+		return err
+	}
+
+	if cfg.Stats.FlushInterval <= 0 {
+		cfg.Stats.FlushInterval = defaultStatsFlushInterval
+	}
+
+	if err := viper.UnmarshalKey("pagination", &cfg.Pagination); err != nil {
+		return err
+	}
+
+	if cfg.Pagination.MaxLimit <= 0 {
+		cfg.Pagination.MaxLimit = defaultMaxLimit
+	}
+
+	if cfg.Pagination.DefaultLimit <= 0 {
+		cfg.Pagination.DefaultLimit = defaultPageLimit
+	}
+
+	if cfg.Pagination.DefaultLimit > cfg.Pagination.MaxLimit {
+		cfg.Pagination.DefaultLimit = cfg.Pagination.MaxLimit
+	}
+
+	if err := viper.UnmarshalKey("events", &cfg.Events); err != nil {
+		return err
+	}
+
+	if cfg.Events.Backend == "" {
+		cfg.Events.Backend = defaultEventsBackend
+	}
+
+	if cfg.Events.Channel == "" {
+		cfg.Events.Channel = defaultEventsChannel
+	}
+
+	if cfg.Events.BufferSize <= 0 {
+		cfg.Events.BufferSize = defaultEventsBufferSize
+	}
+
 	return nil
 }
@@ -20,19 +20,23 @@ type Handler struct {
 	tagsService     service.Tags
 	featuresService service.Features
 	usersService    service.Users
+	jobsService     service.Jobs
+	statsService    service.Stats
 	logger          *logger.Logs
 	tokenManager    auth.TokenManager
 	cache           cache.Cache
 }
 
 func NewHandler(bannersService service.Banners, tagsService service.Tags,
-	featuresService service.Features, usersService service.Users, logger *logger.Logs, tokenManager auth.TokenManager,
-	cache cache.Cache) *Handler {
+	featuresService service.Features, usersService service.Users, jobsService service.Jobs, statsService service.Stats, logger *logger.Logs,
+	tokenManager auth.TokenManager, cache cache.Cache) *Handler {
 	return &Handler{
 		bannersService:  bannersService,
 		tagsService:     tagsService,
 		featuresService: featuresService,
 		usersService:    usersService,
+		jobsService:     jobsService,
+		statsService:    statsService,
 		logger:          logger,
 		tokenManager:    tokenManager,
 		cache:           cache,
@@ -62,7 +66,8 @@ func (h *Handler) Init(host string, port string) *gin.Engine {
 }
 
 func (h *Handler) initAPI(router *gin.Engine) {
-	handlerV1 := httpv1.NewHandler(h.bannersService, h.tagsService, h.featuresService, h.usersService, h.logger, h.tokenManager, h.cache)
+	handlerV1 := httpv1.NewHandler(h.bannersService, h.tagsService, h.featuresService, h.usersService, h.jobsService,
+		h.statsService, h.logger, h.tokenManager, h.cache)
 	api := router.Group("/api")
 	{
 		handlerV1.Init(api)
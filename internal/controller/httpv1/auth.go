@@ -0,0 +1,70 @@
+package httpv1
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+func (h *Handler) initAuthRoutes(api *gin.RouterGroup) {
+	auth := api.Group("/auth")
+	{
+		auth.POST("/refresh", h.refreshTokens)
+		auth.POST("/logout", h.userIdentity, h.logout)
+	}
+}
+
+type refreshInput struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// @Summary Обновление токенов
+// @Description Этот эндпоинт выдает новую пару access и refresh токенов по действующему refresh токену. Старый refresh токен становится недействительным.
+// @Tags auth
+// @ID refresh-tokens
+// @Accept json
+// @Produce json
+// @Param body body refreshInput true "Refresh токен"
+// @Success 200 {object} models.Tokens "OK"
+// @Failure 400 {object} errorResponse "Некорректные данные"
+// @Failure 401 {object} errorResponse "Refresh токен недействителен или истек"
+// @Failure 500 {object} errorResponse "Внутренняя ошибка сервера"
+// @Router /auth/refresh [post]
+func (h *Handler) refreshTokens(ctx *gin.Context) {
+	var input refreshInput
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&input); err != nil {
+		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
+		newErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tokens, errResp := h.usersService.RefreshTokens(ctx, input.RefreshToken)
+	if errResp != nil {
+		h.newServiceErrorResponse(ctx, errResp)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, tokens)
+}
+
+// @Summary Выход из сессии
+// @Description Этот эндпоинт отзывает сессию текущего access токена вместе с ее refresh токеном.
+// @Tags auth
+// @ID logout
+// @Security Bearer
+// @Param Authorization header string true "Bearer token for authentication"
+// @Success 204 {string} string "Сессия завершена"
+// @Failure 401 {object} errorResponse "Пользователь не авторизован"
+// @Failure 500 {object} errorResponse "Внутренняя ошибка сервера"
+// @Router /auth/logout [post]
+func (h *Handler) logout(ctx *gin.Context) {
+	sessionId := ctx.Value(sessionCtx).(int)
+
+	errResp := h.usersService.Logout(ctx, sessionId)
+	if errResp != nil {
+		h.newServiceErrorResponse(ctx, errResp)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
@@ -0,0 +1,134 @@
+package httpv1
+
+import (
+	"avito-test2024-spring/internal/models"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// sseHeartbeatInterval is how often a comment is sent to idle streams, so proxies don't close them.
+const sseHeartbeatInterval = 15 * time.Second
+
+// @Summary Поток изменений баннеров
+// @Tags banner
+// @Description Server-Sent Events поток событий об изменении баннеров: created, updated, toggled, deleted.
+// @Description Каждое событие отправляется с именем banner и JSON телом, клиент после него может перезапросить баннер.
+// @Description Без tag_id админ получает события всех тегов, а пользователь событий тегов своего профиля.
+// @ID stream-banners
+// @Produce text/event-stream
+// @Security Bearer
+// @Param Authorization header string true "Bearer token for authentication"
+// @Param feature_id query integer false "Только события баннеров этой фичи"
+// @Param tag_id query integer false "Explicit tag, any tag for admins and one of own tags for other users"
+// @Success 200 {object} models.BannerEvent "Поток событий"
+// @Failure 400 {object} errorResponse "Некорректные данные"
+// @Failure 401 {object} errorResponse "Пользователь не авторизован"
+// @Failure 403 {object} errorResponse "Пользователь не имеет доступа"
+// @Failure 404 {object} errorResponse "У пользователя нет тегов"
+// @Failure 500 {object} errorResponse "Внутренняя ошибка сервера"
+// @Router /banner/stream [get]
+func (h *Handler) bannersStream(ctx *gin.Context) {
+	featureId, err := parseOptionalFeatureId(ctx.Query("feature_id"))
+	if err != nil {
+		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
+		newErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tagId, err := parseOptionalTagId(ctx.Query("tag_id"))
+	if err != nil {
+		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
+		newErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tagsIds, err := h.streamTags(ctx, tagId)
+	if err != nil {
+		h.newServiceErrorResponse(ctx, err)
+		return
+	}
+
+	// events are received until the client disconnects
+	events, err := h.bannersService.SubscribeChanges(ctx.Request.Context(), models.BannerEventFilter{
+		FeatureId: featureId,
+		TagsIds:   tagsIds,
+	})
+	if err != nil {
+		h.newServiceErrorResponse(ctx, err)
+		return
+	}
+
+	// stream lives longer than server write timeout, error means the writer doesn't support deadlines
+	_ = http.NewResponseController(ctx.Writer).SetWriteDeadline(time.Time{})
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+	ctx.Writer.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+
+			ctx.SSEvent("banner", event)
+		case <-heartbeat.C:
+			if _, err := ctx.Writer.WriteString(": ping\n\n"); err != nil {
+				return
+			}
+		}
+
+		ctx.Writer.Flush()
+	}
+}
+
+// streamTags returns tags of banners streamed to the user. Admins get all tags (nil) unless tagId is given,
+// other users get tags of their profile or one of them.
+func (h *Handler) streamTags(ctx *gin.Context, tagId int) ([]int, error) {
+	isAdmin := ctx.Value(userCtx).(bool)
+	if isAdmin && tagId == 0 {
+		return nil, nil
+	}
+
+	userId := ctx.Value(userIdCtx).(int)
+
+	tagsIds, err := h.userBannerTags(ctx, userId, tagId)
+	if err != nil {
+		return nil, err
+	}
+
+	// empty filter matches any tag, so user without tags must not get it
+	if len(tagsIds) == 0 {
+		return nil, models.NewNotFoundError("user has no tags")
+	}
+
+	return tagsIds, nil
+}
+
+func parseOptionalFeatureId(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	featureId, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid feature_id: %w", err)
+	}
+
+	if featureId <= 0 {
+		return 0, errors.New("feature_id must be greater than 0")
+	}
+
+	return featureId, nil
+}
@@ -0,0 +1,69 @@
+package httpv1
+
+import (
+	"avito-test2024-spring/internal/models"
+	"avito-test2024-spring/internal/service"
+	"context"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// streamBannersService sends the events to the subscriber and closes the stream, other methods are not used.
+type streamBannersService struct {
+	service.Banners
+
+	events []models.BannerEvent
+	filter models.BannerEventFilter
+}
+
+func (s *streamBannersService) SubscribeChanges(ctx context.Context,
+	filter models.BannerEventFilter) (<-chan models.BannerEvent, error) {
+	s.filter = filter
+
+	events := make(chan models.BannerEvent, len(s.events))
+	for _, event := range s.events {
+		events <- event
+	}
+	close(events)
+
+	return events, nil
+}
+
+func TestBannersStream(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	event := models.BannerEvent{Type: models.BannerEventUpdated, BannerId: 1, FeaturesIds: []int{2},
+		TagsIds: []int{3}}
+	bannersService := &streamBannersService{events: []models.BannerEvent{event}}
+	h := NewHandler(bannersService, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/api/v1/banner/stream?feature_id=2", nil)
+	ctx.Set(userCtx, true)
+	ctx.Set(userIdCtx, 1)
+
+	h.bannersStream(ctx)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "text/event-stream", recorder.Header().Get("Content-Type"))
+	require.Equal(t, models.BannerEventFilter{FeatureId: 2}, bannersService.filter)
+
+	frame, _, found := strings.Cut(recorder.Body.String(), "\n\n")
+	require.True(t, found)
+
+	name, data, found := strings.Cut(frame, "\n")
+	require.True(t, found)
+	require.Equal(t, "event:banner", name)
+
+	var received models.BannerEvent
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(data, "data:")), &received))
+	require.Equal(t, event.BannerId, received.BannerId)
+	require.Equal(t, event.FeaturesIds, received.FeaturesIds)
+	require.Equal(t, event.TagsIds, received.TagsIds)
+}
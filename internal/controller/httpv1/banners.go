@@ -2,13 +2,18 @@ package httpv1
 
 import (
 	"avito-test2024-spring/internal/controller/metrics"
+	"avito-test2024-spring/internal/models"
 	"avito-test2024-spring/internal/service"
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 )
 
 func (h *Handler) initBannersRoutes(api *gin.RouterGroup) {
@@ -18,27 +23,37 @@ func (h *Handler) initBannersRoutes(api *gin.RouterGroup) {
 		banners.POST("", h.bannersAdd)
 		banners.PATCH("/:id", h.bannersUpdate)
 		banners.DELETE("/:id", h.bannersDelete)
+		banners.DELETE("", h.bannersDeleteByFeatureTag)
 		banners.GET("", h.bannersGetAll)
+		banners.GET("/:id/versions", h.bannersGetVersions)
+		banners.POST("/:id/versions/:version/activate", h.bannersActivateVersion)
+		banners.POST("/:id/click", h.bannersClick)
+		banners.GET("/:id/stats", h.bannersGetStats)
 	}
 
 	userBanner := api.Group("", h.userIdentity)
 	userBanner.Use(metrics.PrometheusMiddleware())
 	{
 		userBanner.GET("/user_banner", h.getUserBanner)
+		userBanner.GET("/user_banners", h.getUserBanners)
+		userBanner.POST("/user_banners", h.postUserBanners)
 	}
-}
 
-type bannersAddContent struct {
-	Title string `json:"title" binding:"required"`
-	Text  string `json:"text" binding:"required"`
-	URL   string `json:"url" binding:"required"`
+	// stream is long-lived, so it is not measured by request duration metrics
+	stream := api.Group("/banner", h.userIdentity)
+	{
+		stream.GET("/stream", h.bannersStream)
+	}
 }
 
 type bannersAddInput struct {
-	Tags     []int             `json:"tags_ids" binding:"required"`
-	Feature  int               `json:"feature_id" binding:"required"`
-	Content  bannersAddContent `json:"content" binding:"required"`
-	IsActive bool              `json:"is_active" binding:"required"`
+	Tags     []int           `json:"tags_ids" binding:"required"`
+	Feature  int             `json:"feature_id" binding:"required"`
+	Content  json.RawMessage `json:"content" binding:"required" swaggertype:"object"`
+	IsActive bool            `json:"is_active" binding:"required"`
+
+	ActiveFrom  *time.Time `json:"active_from,omitempty"`
+	ActiveUntil *time.Time `json:"active_until,omitempty"`
 }
 
 // CreateBanner creates a new banner.
@@ -52,10 +67,13 @@ type bannersAddInput struct {
 // @Security Bearer
 // @Param Authorization header string true "Bearer token for authentication"
 // @Param body body bannersAddInput true "Banner creation request"
+// @Param dry_run query boolean false "Only validate banner and check conflicts, nothing is created" default(false)
 // @Success 201 {object} int "Banner created successfully"
+// @Success 200 {object} bannersDryRunResponse "Dry run passed, there are no conflicts"
 // @Failure 400 {object} errorResponse "Invalid data provided"
 // @Failure 401 {object} errorResponse "Unauthorized access"
 // @Failure 403 {object} errorResponse "Forbidden access"
+// @Failure 409 {object} errorResponse "Pairs of feature and tag are taken by other banners"
 // @Failure 500 {object} errorResponse "Internal server error"
 // @Router /banner [post]
 func (h *Handler) bannersAdd(ctx *gin.Context) {
@@ -66,6 +84,13 @@ func (h *Handler) bannersAdd(ctx *gin.Context) {
 		return
 	}
 
+	dryRun, err := parseDryRun(ctx)
+	if err != nil {
+		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
+		newErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	var banner bannersAddInput
 	if err := json.NewDecoder(ctx.Request.Body).Decode(&banner); err != nil {
 		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
@@ -74,16 +99,21 @@ func (h *Handler) bannersAdd(ctx *gin.Context) {
 	}
 
 	bannerId, err := h.bannersService.AddBanner(ctx, service.BannerAddInput{
-		Title:    banner.Content.Title,
-		Text:     banner.Content.Text,
-		URL:      banner.Content.URL,
-		Tags:     banner.Tags,
-		Feature:  banner.Feature,
-		IsActive: banner.IsActive,
+		Content:     banner.Content,
+		Tags:        banner.Tags,
+		Feature:     banner.Feature,
+		IsActive:    banner.IsActive,
+		ActiveFrom:  banner.ActiveFrom,
+		ActiveUntil: banner.ActiveUntil,
+		DryRun:      dryRun,
 	})
-	if err.Status != 0 {
-		h.logger.Error(ctx, err.Status, err.Error)
-		newErrorResponse(ctx, err.Status, err.Error)
+	if err != nil {
+		h.newServiceErrorResponse(ctx, err)
+		return
+	}
+
+	if dryRun {
+		ctx.JSON(http.StatusOK, bannersDryRunResponse{Conflicts: []models.BannerConflict{}})
 		return
 	}
 
@@ -100,11 +130,13 @@ func (h *Handler) bannersAdd(ctx *gin.Context) {
 // @Param Authorization header string true "Bearer token for authentication"
 // @Param id path integer true "Идентификатор баннера"
 // @Param body body service.bannersUpdateInput true "Запрос на обновление баннера"
+// @Param dry_run query boolean false "Только проверить данные и конфликты, без изменения баннера" default(false)
 // @Success 200 {string} string "OK"
 // @Failure 400 {object} errorResponse "Некорректные данные"
 // @Failure 401 {object} errorResponse "Пользователь не авторизован"
 // @Failure 403 {object} errorResponse "Пользователь не имеет доступа"
 // @Failure 404 {object} errorResponse "Баннер не найден"
+// @Failure 409 {object} errorResponse "Пары фича-тег заняты другими баннерами"
 // @Failure 500 {object} errorResponse "Внутренняя ошибка сервера"
 // @Router /banner/{id} [patch]
 func (h *Handler) bannersUpdate(ctx *gin.Context) {
@@ -115,19 +147,48 @@ func (h *Handler) bannersUpdate(ctx *gin.Context) {
 		return
 	}
 
+	dryRun, err := parseDryRun(ctx)
+	if err != nil {
+		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
+		newErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	serviceCtx := context.WithValue(ctx, "request_body", ctx.Request.Body)
 	serviceCtx = context.WithValue(serviceCtx, "banner_id", ctx.Param("id"))
+	serviceCtx = context.WithValue(serviceCtx, "dry_run", dryRun)
+
+	err = h.bannersService.UpdateBanner(serviceCtx)
+	if err != nil {
+		h.newServiceErrorResponse(ctx, err)
+		return
+	}
 
-	err := h.bannersService.UpdateBanner(serviceCtx)
-	if err.Status != 0 {
-		h.logger.Error(ctx, err.Status, err.Error)
-		newErrorResponse(ctx, err.Status, err.Error)
+	if dryRun {
+		ctx.JSON(http.StatusOK, bannersDryRunResponse{Conflicts: []models.BannerConflict{}})
 		return
 	}
 
 	ctx.Status(http.StatusOK)
 }
 
+type bannersDryRunResponse struct {
+	Conflicts []models.BannerConflict `json:"conflicts"`
+}
+
+func parseDryRun(ctx *gin.Context) (bool, error) {
+	if ctx.Query("dry_run") == "" {
+		return false, nil
+	}
+
+	dryRun, err := strconv.ParseBool(ctx.Query("dry_run"))
+	if err != nil {
+		return false, errors.New("invalid dry_run format")
+	}
+
+	return dryRun, nil
+}
+
 // DELETE /banner/{id}
 // Удаление баннера по идентификатору
 // @Summary Удаление баннера по идентификатору
@@ -160,28 +221,92 @@ func (h *Handler) bannersDelete(ctx *gin.Context) {
 	}
 
 	errResponse := h.bannersService.DeleteBanner(ctx, bannerId)
-	if errResponse.Status != 0 {
-		h.logger.Error(ctx, errResponse.Status, errResponse.Error)
-		newErrorResponse(ctx, errResponse.Status, errResponse.Error)
+	if errResponse != nil {
+		h.newServiceErrorResponse(ctx, errResponse)
 		return
 	}
 
 	ctx.Status(http.StatusNoContent)
 }
 
-// @Summary Получение всех баннеров c фильтрацией по фиче и/или тегу
+// @Summary Отложенное удаление баннеров по фиче и/или тегу
+// @Description Этот эндпоинт создает задачу на удаление всех баннеров с указанной фичей и/или тегом. Удаление выполняется в фоне, статус задачи доступен по /jobs/{id}.
 // @Tags banner
-// @Description Этот эндпоинт предназначен для получения всех баннеров с возможностью фильтрации по идентификатору фичи и/или тега.
-// @ID get-banners
-// @Accept json
+// @ID delete-banners
 // @Produce json
 // @Security Bearer
 // @Param Authorization header string true "Bearer token for authentication"
 // @Param feature_id query integer false "Идентификатор фичи"
 // @Param tag_id query integer false "Идентификатор тега"
-// @Param limit query integer false "Лимит"
-// @Param offset query integer false "Оффсет"
+// @Success 202 {object} int "Задача на удаление создана"
+// @Failure 400 {object} errorResponse "Некорректные данные"
+// @Failure 401 {object} errorResponse "Пользователь не авторизован"
+// @Failure 403 {object} errorResponse "Пользователь не имеет доступа"
+// @Failure 500 {object} errorResponse "Внутренняя ошибка сервера"
+// @Router /banner [delete]
+func (h *Handler) bannersDeleteByFeatureTag(ctx *gin.Context) {
+	isAdmin := ctx.Value(userCtx).(bool)
+	if !isAdmin {
+		h.logger.Error(ctx, http.StatusForbidden, "Пользователь не имеет доступа")
+		newErrorResponse(ctx, http.StatusForbidden, "Пользователь не имеет доступа")
+		return
+	}
+
+	tagId, err := strconv.Atoi(ctx.Query("tag_id"))
+	if err != nil && errors.Is(err, strconv.ErrSyntax) && ctx.Query("tag_id") != "" {
+		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
+		newErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if ctx.Query("tag_id") == "" {
+		tagId = 0
+	}
+
+	featureId, err := strconv.Atoi(ctx.Query("feature_id"))
+	if err != nil && errors.Is(err, strconv.ErrSyntax) && ctx.Query("feature_id") != "" {
+		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
+		newErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if ctx.Query("feature_id") == "" {
+		featureId = 0
+	}
+
+	jobId, errResponse := h.jobsService.AddBannersDeleteJob(ctx, featureId, tagId)
+	if errResponse != nil {
+		h.newServiceErrorResponse(ctx, errResponse)
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{"job_id": jobId})
+}
+
+// @Summary Получение всех баннеров c фильтрацией, поиском и сортировкой
+// @Tags banner
+// @Description Этот эндпоинт предназначен для получения всех баннеров с возможностью фильтрации по фичам, тегам, активности, окну показа и датам, полнотекстового поиска и сортировки.
+// @ID get-banners
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param Authorization header string true "Bearer token for authentication"
+// @Param feature_id query []integer false "Идентификаторы фич, через запятую или повторением параметра" collectionFormat(csv)
+// @Param tag_id query []integer false "Идентификаторы тегов, через запятую или повторением параметра" collectionFormat(csv)
+// @Param is_active query boolean false "Активность баннера"
+// @Param status query string false "Статус окна показа" Enums(scheduled, live, expired)
+// @Param created_from query string false "Создан не раньше, RFC3339"
+// @Param created_to query string false "Создан не позже, RFC3339"
+// @Param updated_from query string false "Изменен не раньше, RFC3339"
+// @Param updated_to query string false "Изменен не позже, RFC3339"
+// @Param search query string false "Полнотекстовый поиск по title и text содержимого"
+// @Param sort query string false "Поле сортировки" Enums(id, created_at, updated_at) default(id)
+// @Param order query string false "Направление сортировки" Enums(asc, desc) default(asc)
+// @Param limit query integer false "Размер страницы, по умолчанию pagination.defaultLimit, не больше pagination.maxLimit"
+// @Param cursor query string false "Курсор следующей страницы из заголовка X-Next-Cursor"
 // @Success 200 {array} models.AdminBanner "OK"
+// @Header 200 {integer} X-Total-Count "Общее количество записей"
+// @Header 200 {string} X-Next-Cursor "Курсор следующей страницы, отсутствует на последней странице"
 // @Failure 401 {object} errorResponse "Пользователь не авторизован"
 // @Failure 403 {object} errorResponse "Пользователь не имеет доступа"
 // @Failure 500 {object} errorResponse "Внутренняя ошибка сервера"
@@ -194,72 +319,212 @@ func (h *Handler) bannersGetAll(ctx *gin.Context) {
 		return
 	}
 
-	limit, err := strconv.Atoi(ctx.Query("limit"))
-	if err != nil && errors.Is(err, strconv.ErrSyntax) && ctx.Query("limit") != "" {
+	page, err := parsePageRequest(ctx)
+	if err != nil {
 		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
 		newErrorResponse(ctx, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	if ctx.Query("limit") == "" {
-		limit = 0
-	}
-
-	offset, err := strconv.Atoi(ctx.Query("offset"))
-	if err != nil && errors.Is(err, strconv.ErrSyntax) && ctx.Query("offset") != "" {
+	filter, err := parseBannerFilter(ctx)
+	if err != nil {
 		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
 		newErrorResponse(ctx, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	if ctx.Query("offset") == "" {
-		offset = 0
+	banners, pageInfo, errResponse := h.bannersService.GetAllBanners(ctx, filter, page)
+	if errResponse != nil {
+		h.newServiceErrorResponse(ctx, errResponse)
+		return
 	}
 
-	tagId, err := strconv.Atoi(ctx.Query("tag_id"))
-	if err != nil && errors.Is(err, strconv.ErrSyntax) && ctx.Query("tag_id") != "" {
+	setPageHeaders(ctx, pageInfo)
+
+	ctx.JSON(http.StatusOK, banners)
+}
+
+// parseBannerFilter reads filters and sort of banners list from query.
+func parseBannerFilter(ctx *gin.Context) (models.BannerFilter, error) {
+	var filter models.BannerFilter
+	var err error
+
+	filter.FeaturesIds, err = parseIdsQuery(ctx, "feature_id")
+	if err != nil {
+		return models.BannerFilter{}, err
+	}
+
+	filter.TagsIds, err = parseIdsQuery(ctx, "tag_id")
+	if err != nil {
+		return models.BannerFilter{}, err
+	}
+
+	if ctx.Query("is_active") != "" {
+		isActive, err := strconv.ParseBool(ctx.Query("is_active"))
+		if err != nil {
+			return models.BannerFilter{}, fmt.Errorf("invalid is_active: %w", err)
+		}
+		filter.IsActive = &isActive
+	}
+
+	filter.Status = ctx.Query("status")
+
+	for name, bound := range map[string]**time.Time{
+		"created_from": &filter.CreatedFrom,
+		"created_to":   &filter.CreatedTo,
+		"updated_from": &filter.UpdatedFrom,
+		"updated_to":   &filter.UpdatedTo,
+	} {
+		if ctx.Query(name) == "" {
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339, ctx.Query(name))
+		if err != nil {
+			return models.BannerFilter{}, fmt.Errorf("invalid %v: %w", name, err)
+		}
+		*bound = &t
+	}
+
+	filter.Search = strings.TrimSpace(ctx.Query("search"))
+	filter.SortBy = ctx.Query("sort")
+
+	switch ctx.Query("order") {
+	case "", "asc":
+	case "desc":
+		filter.SortDesc = true
+	default:
+		return models.BannerFilter{}, errors.New("order must be asc or desc")
+	}
+
+	return filter, nil
+}
+
+// parseIdsQuery reads ids from query parameter which is repeated or contains comma separated ids.
+func parseIdsQuery(ctx *gin.Context, name string) ([]int, error) {
+	ids := make([]int, 0)
+
+	for _, value := range ctx.QueryArray(name) {
+		for _, part := range strings.Split(value, ",") {
+			if part == "" {
+				continue
+			}
+
+			id, err := strconv.Atoi(part)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %v: %w", name, err)
+			}
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
+
+// @Summary Получение версий баннера
+// @Tags banner
+// @Description Этот эндпоинт предназначен для получения сохраненных версий баннера, начиная с последней.
+// @ID get-banner-versions
+// @Produce json
+// @Security Bearer
+// @Param Authorization header string true "Bearer token for authentication"
+// @Param id path integer true "Идентификатор баннера"
+// @Success 200 {array} models.BannerVersion "OK"
+// @Failure 400 {object} errorResponse "Некорректные данные"
+// @Failure 401 {object} errorResponse "Пользователь не авторизован"
+// @Failure 403 {object} errorResponse "Пользователь не имеет доступа"
+// @Failure 404 {object} errorResponse "Баннер не найден"
+// @Failure 500 {object} errorResponse "Внутренняя ошибка сервера"
+// @Router /banner/{id}/versions [get]
+func (h *Handler) bannersGetVersions(ctx *gin.Context) {
+	isAdmin := ctx.Value(userCtx).(bool)
+	if !isAdmin {
+		h.logger.Error(ctx, http.StatusForbidden, "Пользователь не имеет доступа")
+		newErrorResponse(ctx, http.StatusForbidden, "Пользователь не имеет доступа")
+		return
+	}
+
+	bannerId, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
 		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
 		newErrorResponse(ctx, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	if ctx.Query("tag_id") == "" {
-		tagId = 0
+	versions, errResponse := h.bannersService.GetBannerVersions(ctx, bannerId)
+	if errResponse != nil {
+		h.newServiceErrorResponse(ctx, errResponse)
+		return
 	}
 
-	featureId, err := strconv.Atoi(ctx.Query("feature_id"))
-	if err != nil && errors.Is(err, strconv.ErrSyntax) && ctx.Query("feature_id") != "" {
+	ctx.JSON(http.StatusOK, versions)
+}
+
+// @Summary Откат баннера к одной из предыдущих версий
+// @Tags banner
+// @Description Этот эндпоинт предназначен для восстановления содержимого, фичи, тэгов и активности баннера из выбранной версии.
+// @ID activate-banner-version
+// @Security Bearer
+// @Param Authorization header string true "Bearer token for authentication"
+// @Param id path integer true "Идентификатор баннера"
+// @Param version path integer true "Номер версии баннера"
+// @Success 200 {string} string "OK"
+// @Failure 400 {object} errorResponse "Некорректные данные"
+// @Failure 401 {object} errorResponse "Пользователь не авторизован"
+// @Failure 403 {object} errorResponse "Пользователь не имеет доступа"
+// @Failure 404 {object} errorResponse "Версия баннера не найдена"
+// @Failure 500 {object} errorResponse "Внутренняя ошибка сервера"
+// @Router /banner/{id}/versions/{version}/activate [post]
+func (h *Handler) bannersActivateVersion(ctx *gin.Context) {
+	isAdmin := ctx.Value(userCtx).(bool)
+	if !isAdmin {
+		h.logger.Error(ctx, http.StatusForbidden, "Пользователь не имеет доступа")
+		newErrorResponse(ctx, http.StatusForbidden, "Пользователь не имеет доступа")
+		return
+	}
+
+	bannerId, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
 		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
 		newErrorResponse(ctx, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	if ctx.Query("feature_id") == "" {
-		featureId = 0
+	version, err := strconv.Atoi(ctx.Param("version"))
+	if err != nil {
+		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
+		newErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	banners, errResponse := h.bannersService.GetAllBanners(ctx, featureId, tagId, limit, offset)
-	if errResponse.Status != 0 {
-		h.logger.Error(ctx, errResponse.Status, errResponse.Error)
-		newErrorResponse(ctx, errResponse.Status, errResponse.Error)
+	errResponse := h.bannersService.ActivateBannerVersion(ctx, bannerId, version)
+	if errResponse != nil {
+		h.newServiceErrorResponse(ctx, errResponse)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, banners)
+	ctx.Status(http.StatusOK)
 }
 
 // @Summary Получение баннера для пользователя
 // @Tags banner
-// @Description This endpoint allows a user to get a banner based on their tag and feature ID.
+// @Description This endpoint allows a user to get a banner based on tags of their profile and feature ID.
+// @Description Tags are tried by priority and the banner of the first tag which has one is returned.
+// @Description tag_id selects the tag explicitly: admins can preview banner of any tag, other users only of their own tags.
 // @ID get-user-banner
 // @Accept json
 // @Produce json
 // @Security Bearer
 // @Param Authorization header string true "Bearer token for authentication"
-// @Param tag_id query integer true "User tag"
+// @Param tag_id query integer false "Explicit tag, any tag for admins and one of own tags for other users"
 // @Param feature_id query integer true "Feature ID"
 // @Param use_last_revision query boolean false "Get the latest information" default(false)
+// @Param If-None-Match header string false "ETag of banner the client already has"
 // @Success 200 {object} models.Banner "User banner"
+// @Success 304 {string} string "Banner is not modified"
+// @Header 200,304 {integer} X-Banner-Variant "Banner id of A/B experiment variant, absent if there is no experiment"
+// @Header 200,304 {string} ETag "Hash of banner content"
+// @Header 200,304 {string} Cache-Control "private, max-age is the remaining lifetime of the cached banner"
 // @Failure 400 {object} errorResponse "Invalid data provided"
 // @Failure 401 {object} errorResponse "Unauthorized access"
 // @Failure 403 {object} errorResponse "Forbidden access"
@@ -267,52 +532,110 @@ func (h *Handler) bannersGetAll(ctx *gin.Context) {
 // @Failure 500 {object} errorResponse "Internal server error"
 // @Router /user_banner [get]
 func (h *Handler) getUserBanner(ctx *gin.Context) {
-	tagId, err := strconv.Atoi(ctx.Query("tag_id"))
-	if err != nil && errors.Is(err, strconv.ErrSyntax) && ctx.Query("tag_id") != "" {
+	featureId, err := strconv.Atoi(ctx.Query("feature_id"))
+	if err != nil && errors.Is(err, strconv.ErrSyntax) && ctx.Query("feature_id") != "" {
 		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
 		newErrorResponse(ctx, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	if ctx.Query("tag_id") == "" {
+	if ctx.Query("feature_id") == "" {
 		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
 		newErrorResponse(ctx, http.StatusBadRequest, "empty tag_id field")
 		return
 	}
 
-	featureId, err := strconv.Atoi(ctx.Query("feature_id"))
-	if err != nil && errors.Is(err, strconv.ErrSyntax) && ctx.Query("feature_id") != "" {
+	lastRevision, err := parseLastRevision(ctx.Query("use_last_revision"))
+	if err != nil {
 		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
 		newErrorResponse(ctx, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	if ctx.Query("feature_id") == "" {
+	tagId, err := parseOptionalTagId(ctx.Query("tag_id"))
+	if err != nil {
 		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
-		newErrorResponse(ctx, http.StatusBadRequest, "empty tag_id field")
+		newErrorResponse(ctx, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	lastRevision := false
-	lastRevisionQuery := ctx.Query("use_last_revision")
-	if lastRevisionQuery != "" {
-		if lastRevisionQuery == "true" {
-			lastRevision = true
-		} else {
-			if lastRevisionQuery != "false" {
-				h.logger.Error(ctx, http.StatusBadRequest, err.Error())
-				newErrorResponse(ctx, http.StatusBadRequest, "invalid last_revision format")
-				return
-			}
-		}
+	userId := ctx.Value(userIdCtx).(int)
+
+	tagsIds, err := h.userBannerTags(ctx, userId, tagId)
+	if err != nil {
+		h.newServiceErrorResponse(ctx, err)
+		return
+	}
+
+	banner, errResponse := h.bannersService.GetUserBanner(ctx, featureId, tagsIds, userId, lastRevision)
+	if errResponse != nil {
+		h.newServiceErrorResponse(ctx, errResponse)
+		return
+	}
+
+	if banner.Variant != 0 {
+		ctx.Header(bannerVariantHeader, strconv.Itoa(banner.Variant))
 	}
 
-	banner, errResponse := h.bannersService.GetUserBanner(ctx, featureId, tagId, lastRevision)
-	if errResponse.Status != 0 {
-		h.logger.Error(ctx, errResponse.Status, errResponse.Error)
-		newErrorResponse(ctx, errResponse.Status, errResponse.Error)
+	setCacheHeaders(ctx, banner.ETag, banner.ExpiresAt)
+	if etagMatches(ctx.GetHeader("If-None-Match"), banner.ETag) {
+		ctx.Status(http.StatusNotModified)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, banner)
+	ctx.JSON(http.StatusOK, banner.Content)
+}
+
+// userBannerTags returns tags used to choose banner for the user. Without explicit tagId (0) these are tags
+// stored in the user profile ordered by priority. Explicit tagId is allowed for admins previewing banner of any tag
+// and for users only if it is one of their tags.
+func (h *Handler) userBannerTags(ctx *gin.Context, userId int, tagId int) ([]int, error) {
+	if tagId == 0 {
+		return h.usersService.GetUserTags(ctx, userId)
+	}
+
+	isAdmin := ctx.Value(userCtx).(bool)
+	if isAdmin {
+		return []int{tagId}, nil
+	}
+
+	tagsIds, err := h.usersService.GetUserTags(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !slices.Contains(tagsIds, tagId) {
+		return nil, models.NewForbiddenError(fmt.Sprintf("tag_id=%v is not a tag of the user", tagId))
+	}
+
+	return []int{tagId}, nil
+}
+
+// parseOptionalTagId parses explicit tag_id of user banner request, empty value is returned as 0.
+func parseOptionalTagId(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	tagId, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid tag_id: %w", err)
+	}
+
+	if tagId <= 0 {
+		return 0, errors.New("tag_id must be greater than 0")
+	}
+
+	return tagId, nil
+}
+
+func parseLastRevision(value string) (bool, error) {
+	switch value {
+	case "", "false":
+		return false, nil
+	case "true":
+		return true, nil
+	default:
+		return false, errors.New("invalid last_revision format")
+	}
 }
@@ -8,6 +8,7 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"os"
 	"strings"
 	"testing"
 )
@@ -59,44 +60,27 @@ func TestUserBanner(t *testing.T) {
 
 	client := &http.Client{}
 
-	r1Body := make(map[string]interface{})
-	r1Body["is_admin"] = true
-	r1BodyJSON, err := json.Marshal(r1Body)
-	require.NoError(t, err)
-
-	r1, err := http.NewRequest(http.MethodPost,
-		fmt.Sprintf("http://%v:%v/api/v1/users", "localhost", "8080"), strings.NewReader(string(r1BodyJSON)))
-	r1.Header.Add("Content-Type", "application/json")
-
-	resp, err := client.Do(r1)
-	require.NoError(t, err)
-
-	if resp.StatusCode != 201 || resp.Body == nil {
-		log.Fatal(resp.Status)
+	// users API is available only for admins, so the first admin is created with `banners-api create-admin`
+	adminAccessToken := os.Getenv("ADMIN_ACCESS_TOKEN")
+	if adminAccessToken == "" {
+		t.Skip("ADMIN_ACCESS_TOKEN is not set")
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	require.NoError(t, err)
-
-	accessToken := make(map[string]string)
-	if err := json.Unmarshal(body, &accessToken); err != nil {
-		fmt.Println("r1")
-		log.Fatal(err)
-	}
+	accessToken := map[string]string{"access_token": adminAccessToken}
 
 	r2, err := http.NewRequest(http.MethodPost,
 		fmt.Sprintf("http://%v:%v/api/v1/tags", "localhost", "8080"), nil)
 	r2.Header.Add("Content-Type", "application/json")
 	r2.Header.Add("Authorization", fmt.Sprintf("Bearer %v", accessToken["access_token"]))
 
-	resp, err = client.Do(r2)
+	resp, err := client.Do(r2)
 	require.NoError(t, err)
 
 	if resp.StatusCode != 201 || resp.Body == nil {
 		log.Fatal(resp.Status)
 	}
 
-	body, err = io.ReadAll(resp.Body)
+	body, err := io.ReadAll(resp.Body)
 	require.NoError(t, err)
 
 	tagId := make(map[string]int)
@@ -133,6 +117,7 @@ func TestUserBanner(t *testing.T) {
 	r4, err := http.NewRequest(http.MethodPost,
 		fmt.Sprintf("http://%v:%v/api/v1/users", "localhost", "8080"), strings.NewReader(string(r4BodyJSON)))
 	r4.Header.Add("Content-Type", "application/json")
+	r4.Header.Add("Authorization", fmt.Sprintf("Bearer %v", accessToken["access_token"]))
 
 	resp, err = client.Do(r4)
 	require.NoError(t, err)
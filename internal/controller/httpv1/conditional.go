@@ -0,0 +1,36 @@
+package httpv1
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"strings"
+	"time"
+)
+
+// setCacheHeaders sets ETag and lets clients keep the response while the banner stays in server cache.
+// Banner depends on the user, so shared caches must not store it.
+func setCacheHeaders(ctx *gin.Context, etag string, expiresAt time.Time) {
+	maxAge := int(time.Until(expiresAt).Seconds())
+	if maxAge < 0 {
+		maxAge = 0
+	}
+
+	ctx.Header("ETag", etag)
+	ctx.Header("Cache-Control", fmt.Sprintf("private, max-age=%v", maxAge))
+}
+
+// etagMatches reports whether If-None-Match header contains etag. Weak comparison is used as RFC 9110 requires.
+func etagMatches(ifNoneMatch string, etag string) bool {
+	if ifNoneMatch == "" || etag == "" {
+		return false
+	}
+
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,19 @@
+package httpv1
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestEtagMatches(t *testing.T) {
+	etag := `"0123456789abcdef"`
+
+	require.True(t, etagMatches(`"0123456789abcdef"`, etag))
+	require.True(t, etagMatches(`W/"0123456789abcdef"`, etag))
+	require.True(t, etagMatches(`"other", "0123456789abcdef"`, etag))
+	require.True(t, etagMatches(`*`, etag))
+
+	require.False(t, etagMatches(``, etag))
+	require.False(t, etagMatches(`"other"`, etag))
+	require.False(t, etagMatches(`"0123456789abcdef"`, ``))
+}
@@ -1,11 +1,50 @@
 package httpv1
 
-import "github.com/gin-gonic/gin"
+import (
+	"avito-test2024-spring/internal/models"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
 
 type errorResponse struct {
-	Message string `json:"error"`
+	Message   string                  `json:"error"`
+	Conflicts []models.BannerConflict `json:"conflicts,omitempty"`
 }
 
 func newErrorResponse(ctx *gin.Context, statusCode int, message string) {
-	ctx.AbortWithStatusJSON(statusCode, errorResponse{message})
+	ctx.AbortWithStatusJSON(statusCode, errorResponse{Message: message})
+}
+
+// newServiceErrorResponse logs error returned by service and responds with status of its kind.
+func (h *Handler) newServiceErrorResponse(ctx *gin.Context, err error) {
+	status := errorStatus(err)
+
+	h.logger.Error(ctx, status, err.Error())
+
+	var conflictsErr *models.BannerConflictsError
+	if errors.As(err, &conflictsErr) {
+		ctx.AbortWithStatusJSON(status, errorResponse{Message: err.Error(), Conflicts: conflictsErr.Conflicts})
+		return
+	}
+
+	newErrorResponse(ctx, status, err.Error())
+}
+
+// errorStatus maps domain errors to HTTP statuses, errors of unknown kind are internal.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, models.ErrValidation):
+		return http.StatusBadRequest
+	case errors.Is(err, models.ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, models.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, models.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, models.ErrConflict):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
 }
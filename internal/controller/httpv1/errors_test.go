@@ -0,0 +1,29 @@
+package httpv1
+
+import (
+	"avito-test2024-spring/internal/models"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+)
+
+func TestErrorStatus(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+	}{
+		{models.NewValidationError("tag_id must be greater than 0"), http.StatusBadRequest},
+		{models.NewUnauthorizedError("Пользователь не авторизован"), http.StatusUnauthorized},
+		{models.NewForbiddenError("Пользователь не имеет доступа"), http.StatusForbidden},
+		{models.NewNotFoundError("banner with id=1 not found"), http.StatusNotFound},
+		{models.NewConflictError("banner with such feature_id and tag_id already exists"), http.StatusConflict},
+		{fmt.Errorf("update banner: %w", models.NewNotFoundError("banner with id=1 not found")), http.StatusNotFound},
+		{errors.New("connection refused"), http.StatusInternalServerError},
+	}
+
+	for _, test := range tests {
+		require.Equal(t, test.status, errorStatus(test.err), test.err.Error())
+	}
+}
@@ -0,0 +1,172 @@
+package httpv1
+
+import (
+	"avito-test2024-spring/internal/models"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+// bannerVariantHeader reports which variant of A/B experiment was served to user
+const bannerVariantHeader = "X-Banner-Variant"
+
+func (h *Handler) initExperimentsRoutes(api *gin.RouterGroup) {
+	experiments := api.Group("/experiments", h.userIdentity)
+	{
+		experiments.GET("", h.experimentsGet)
+		experiments.PUT("", h.experimentsSet)
+		experiments.DELETE("", h.experimentsDelete)
+	}
+}
+
+type experimentsSetInput struct {
+	Variants []models.Variant `json:"variants" binding:"required"`
+}
+
+type experimentResponse struct {
+	FeatureId int              `json:"feature_id"`
+	TagId     int              `json:"tag_id"`
+	Variants  []models.Variant `json:"variants"`
+}
+
+// parseExperimentSlot reads feature_id and tag_id of experiment from query, both are required.
+func parseExperimentSlot(ctx *gin.Context) (int, int, error) {
+	featureId, err := strconv.Atoi(ctx.Query("feature_id"))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid feature_id: %w", err)
+	}
+
+	tagId, err := strconv.Atoi(ctx.Query("tag_id"))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid tag_id: %w", err)
+	}
+
+	return featureId, tagId, nil
+}
+
+// @Summary Получение A/B эксперимента
+// @Description Этот эндпоинт предназначен для получения вариантов баннеров и их весов для фичи и тега.
+// @Tags experiment
+// @ID get-experiment
+// @Produce json
+// @Security Bearer
+// @Param Authorization header string true "Bearer token for authentication"
+// @Param feature_id query integer true "Идентификатор фичи"
+// @Param tag_id query integer true "Идентификатор тега"
+// @Success 200 {object} experimentResponse "OK"
+// @Failure 400 {object} errorResponse "Некорректные данные"
+// @Failure 401 {object} errorResponse "Пользователь не авторизован"
+// @Failure 403 {object} errorResponse "Пользователь не имеет доступа"
+// @Failure 404 {object} errorResponse "Эксперимент не найден"
+// @Failure 500 {object} errorResponse "Внутренняя ошибка сервера"
+// @Router /experiments [get]
+func (h *Handler) experimentsGet(ctx *gin.Context) {
+	isAdmin := ctx.Value(userCtx).(bool)
+	if !isAdmin {
+		h.logger.Error(ctx, http.StatusForbidden, "Пользователь не имеет доступа")
+		newErrorResponse(ctx, http.StatusForbidden, "Пользователь не имеет доступа")
+		return
+	}
+
+	featureId, tagId, err := parseExperimentSlot(ctx)
+	if err != nil {
+		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
+		newErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	variants, errResponse := h.bannersService.GetVariants(ctx, featureId, tagId)
+	if errResponse != nil {
+		h.newServiceErrorResponse(ctx, errResponse)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, experimentResponse{FeatureId: featureId, TagId: tagId, Variants: variants})
+}
+
+// @Summary Создание или изменение A/B эксперимента
+// @Description Этот эндпоинт задает варианты баннеров с весами для фичи и тега. Пользователь всегда получает один и тот же вариант, пока эксперимент не изменен.
+// @Tags experiment
+// @ID set-experiment
+// @Accept json
+// @Security Bearer
+// @Param Authorization header string true "Bearer token for authentication"
+// @Param feature_id query integer true "Идентификатор фичи"
+// @Param tag_id query integer true "Идентификатор тега"
+// @Param input body experimentsSetInput true "Варианты эксперимента"
+// @Success 204 "No Content"
+// @Failure 400 {object} errorResponse "Некорректные данные"
+// @Failure 401 {object} errorResponse "Пользователь не авторизован"
+// @Failure 403 {object} errorResponse "Пользователь не имеет доступа"
+// @Failure 500 {object} errorResponse "Внутренняя ошибка сервера"
+// @Router /experiments [put]
+func (h *Handler) experimentsSet(ctx *gin.Context) {
+	isAdmin := ctx.Value(userCtx).(bool)
+	if !isAdmin {
+		h.logger.Error(ctx, http.StatusForbidden, "Пользователь не имеет доступа")
+		newErrorResponse(ctx, http.StatusForbidden, "Пользователь не имеет доступа")
+		return
+	}
+
+	featureId, tagId, err := parseExperimentSlot(ctx)
+	if err != nil {
+		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
+		newErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var input experimentsSetInput
+	if err := ctx.BindJSON(&input); err != nil {
+		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
+		newErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	errResponse := h.bannersService.SetVariants(ctx, featureId, tagId, input.Variants)
+	if errResponse != nil {
+		h.newServiceErrorResponse(ctx, errResponse)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// @Summary Завершение A/B эксперимента
+// @Description Этот эндпоинт удаляет варианты баннеров для фичи и тега, после чего пользователям показывается обычный баннер.
+// @Tags experiment
+// @ID delete-experiment
+// @Security Bearer
+// @Param Authorization header string true "Bearer token for authentication"
+// @Param feature_id query integer true "Идентификатор фичи"
+// @Param tag_id query integer true "Идентификатор тега"
+// @Success 204 "No Content"
+// @Failure 400 {object} errorResponse "Некорректные данные"
+// @Failure 401 {object} errorResponse "Пользователь не авторизован"
+// @Failure 403 {object} errorResponse "Пользователь не имеет доступа"
+// @Failure 404 {object} errorResponse "Эксперимент не найден"
+// @Failure 500 {object} errorResponse "Внутренняя ошибка сервера"
+// @Router /experiments [delete]
+func (h *Handler) experimentsDelete(ctx *gin.Context) {
+	isAdmin := ctx.Value(userCtx).(bool)
+	if !isAdmin {
+		h.logger.Error(ctx, http.StatusForbidden, "Пользователь не имеет доступа")
+		newErrorResponse(ctx, http.StatusForbidden, "Пользователь не имеет доступа")
+		return
+	}
+
+	featureId, tagId, err := parseExperimentSlot(ctx)
+	if err != nil {
+		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
+		newErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	errResponse := h.bannersService.DeleteVariants(ctx, featureId, tagId)
+	if errResponse != nil {
+		h.newServiceErrorResponse(ctx, errResponse)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
@@ -13,19 +13,23 @@ type Handler struct {
 	tagsService     service.Tags
 	featuresService service.Features
 	usersService    service.Users
+	jobsService     service.Jobs
+	statsService    service.Stats
 	logger          *logger.Logs
 	tokenManager    auth.TokenManager
 	cache           cache.Cache
 }
 
 func NewHandler(bannersService service.Banners, tagsService service.Tags,
-	featuresService service.Features, usersService service.Users, logger *logger.Logs, tokenManager auth.TokenManager,
-	cache cache.Cache) *Handler {
+	featuresService service.Features, usersService service.Users, jobsService service.Jobs, statsService service.Stats, logger *logger.Logs,
+	tokenManager auth.TokenManager, cache cache.Cache) *Handler {
 	return &Handler{
 		bannersService:  bannersService,
 		tagsService:     tagsService,
 		featuresService: featuresService,
 		usersService:    usersService,
+		jobsService:     jobsService,
+		statsService:    statsService,
 		logger:          logger,
 		tokenManager:    tokenManager,
 		cache:           cache,
@@ -38,5 +42,8 @@ func (h *Handler) Init(api *gin.RouterGroup) {
 		h.initBannersRoutes(v1)
 		h.initTagsFeaturesRoutes(v1)
 		h.initUsersRoutes(v1)
+		h.initAuthRoutes(v1)
+		h.initJobsRoutes(v1)
+		h.initExperimentsRoutes(v1)
 	}
 }
@@ -0,0 +1,53 @@
+package httpv1
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+func (h *Handler) initJobsRoutes(api *gin.RouterGroup) {
+	jobs := api.Group("/jobs", h.userIdentity)
+	{
+		jobs.GET("/:id", h.getJob)
+	}
+}
+
+// @Summary Получение статуса фоновой задачи
+// @Description Этот эндпоинт предназначен для получения статуса, прогресса и ошибки фоновой задачи по ее идентификатору.
+// @Tags job
+// @ID get-job
+// @Produce json
+// @Security Bearer
+// @Param Authorization header string true "Bearer token for authentication"
+// @Param id path integer true "Идентификатор задачи"
+// @Success 200 {object} models.Job "OK"
+// @Failure 400 {object} errorResponse "Некорректные данные"
+// @Failure 401 {object} errorResponse "Пользователь не авторизован"
+// @Failure 403 {object} errorResponse "Пользователь не имеет доступа"
+// @Failure 404 {object} errorResponse "Задача не найдена"
+// @Failure 500 {object} errorResponse "Внутренняя ошибка сервера"
+// @Router /jobs/{id} [get]
+func (h *Handler) getJob(ctx *gin.Context) {
+	isAdmin := ctx.Value(userCtx).(bool)
+	if !isAdmin {
+		h.logger.Error(ctx, http.StatusForbidden, "Пользователь не имеет доступа")
+		newErrorResponse(ctx, http.StatusForbidden, "Пользователь не имеет доступа")
+		return
+	}
+
+	jobId, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
+		newErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	job, errResponse := h.jobsService.GetJob(ctx, jobId)
+	if errResponse != nil {
+		h.newServiceErrorResponse(ctx, errResponse)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, job)
+}
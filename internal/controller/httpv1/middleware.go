@@ -10,6 +10,8 @@ import (
 const (
 	authorizationHeader = "Authorization"
 	userCtx             = "userRole"
+	userIdCtx           = "userId"
+	sessionCtx          = "sessionId"
 )
 
 func (h *Handler) userIdentity(ctx *gin.Context) {
@@ -30,28 +32,31 @@ func (h *Handler) userIdentity(ctx *gin.Context) {
 		return
 	}
 
-	userIdStr, err := h.tokenManager.Parse(headerParts[1])
+	claims, err := h.tokenManager.Parse(headerParts[1])
 	if err != nil {
 		newErrorResponse(ctx, http.StatusUnauthorized, err.Error())
 		return
 	}
 
-	userId, err := strconv.Atoi(userIdStr)
+	userId, err := strconv.Atoi(claims.UserId)
 	if err != nil {
 		newErrorResponse(ctx, http.StatusUnauthorized, "Пользователь не авторизован")
 		return
 	}
 
-	user, errResponse := h.usersService.GetUserById(ctx, userId)
-	if errResponse.Status != 0 {
-		if errResponse.Status == http.StatusNotFound {
-			newErrorResponse(ctx, http.StatusUnauthorized, "Пользователь не авторизован")
-			return
-		}
+	sessionId, err := strconv.Atoi(claims.SessionId)
+	if err != nil {
+		newErrorResponse(ctx, http.StatusUnauthorized, "Пользователь не авторизован")
+		return
+	}
 
-		newErrorResponse(ctx, errResponse.Status, errResponse.Error)
+	errResponse := h.usersService.ValidateSession(ctx, userId, sessionId, claims.IsAdmin)
+	if errResponse != nil {
+		newErrorResponse(ctx, errorStatus(errResponse), errResponse.Error())
 		return
 	}
 
-	ctx.Set(userCtx, user.IsAdmin)
+	ctx.Set(userCtx, claims.IsAdmin)
+	ctx.Set(userIdCtx, userId)
+	ctx.Set(sessionCtx, sessionId)
 }
@@ -0,0 +1,42 @@
+package httpv1
+
+import (
+	"avito-test2024-spring/internal/models"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"strconv"
+)
+
+const (
+	totalCountHeader = "X-Total-Count"
+	nextCursorHeader = "X-Next-Cursor"
+)
+
+// parsePageRequest reads limit and cursor of admin list from query.
+func parsePageRequest(ctx *gin.Context) (models.PageRequest, error) {
+	page := models.PageRequest{Cursor: ctx.Query("cursor")}
+
+	if ctx.Query("limit") != "" {
+		limit, err := strconv.Atoi(ctx.Query("limit"))
+		if err != nil {
+			return models.PageRequest{}, fmt.Errorf("invalid limit: %w", err)
+		}
+
+		if limit <= 0 {
+			return models.PageRequest{}, fmt.Errorf("limit must be greater than 0")
+		}
+
+		page.Limit = limit
+	}
+
+	return page, nil
+}
+
+// setPageHeaders reports total number of items and cursor of the next page, which is absent on the last page.
+func setPageHeaders(ctx *gin.Context, info models.PageInfo) {
+	ctx.Header(totalCountHeader, strconv.Itoa(info.Total))
+
+	if info.NextCursor != "" {
+		ctx.Header(nextCursorHeader, info.NextCursor)
+	}
+}
@@ -0,0 +1,102 @@
+package httpv1
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// defaultStatsDays is the number of days returned by stats endpoint when from is not set
+const defaultStatsDays = 7
+
+// @Summary Регистрация клика по баннеру
+// @Tags banner
+// @Description Этот эндпоинт предназначен для учета клика пользователя по баннеру.
+// @ID click-banner
+// @Security Bearer
+// @Param Authorization header string true "Bearer token for authentication"
+// @Param id path integer true "Идентификатор баннера"
+// @Success 204 "No Content"
+// @Failure 400 {object} errorResponse "Некорректные данные"
+// @Failure 401 {object} errorResponse "Пользователь не авторизован"
+// @Failure 404 {object} errorResponse "Баннер не найден"
+// @Failure 500 {object} errorResponse "Внутренняя ошибка сервера"
+// @Router /banner/{id}/click [post]
+func (h *Handler) bannersClick(ctx *gin.Context) {
+	bannerId, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
+		newErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	errResponse := h.statsService.RecordClick(ctx, bannerId)
+	if errResponse != nil {
+		h.newServiceErrorResponse(ctx, errResponse)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// @Summary Статистика показов и кликов баннера
+// @Tags banner
+// @Description Этот эндпоинт предназначен для получения показов, кликов и CTR баннера по дням (UTC). Дни без показов и кликов не возвращаются.
+// @ID get-banner-stats
+// @Produce json
+// @Security Bearer
+// @Param Authorization header string true "Bearer token for authentication"
+// @Param id path integer true "Идентификатор баннера"
+// @Param from query string false "Первый день в формате YYYY-MM-DD, по умолчанию 6 дней до to"
+// @Param to query string false "Последний день в формате YYYY-MM-DD, по умолчанию сегодня"
+// @Success 200 {array} models.BannerStats "OK"
+// @Failure 400 {object} errorResponse "Некорректные данные"
+// @Failure 401 {object} errorResponse "Пользователь не авторизован"
+// @Failure 403 {object} errorResponse "Пользователь не имеет доступа"
+// @Failure 500 {object} errorResponse "Внутренняя ошибка сервера"
+// @Router /banner/{id}/stats [get]
+func (h *Handler) bannersGetStats(ctx *gin.Context) {
+	isAdmin := ctx.Value(userCtx).(bool)
+	if !isAdmin {
+		h.logger.Error(ctx, http.StatusForbidden, "Пользователь не имеет доступа")
+		newErrorResponse(ctx, http.StatusForbidden, "Пользователь не имеет доступа")
+		return
+	}
+
+	bannerId, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
+		newErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	to := time.Now().UTC()
+	if ctx.Query("to") != "" {
+		to, err = time.Parse(time.DateOnly, ctx.Query("to"))
+		if err != nil {
+			h.logger.Error(ctx, http.StatusBadRequest, err.Error())
+			newErrorResponse(ctx, http.StatusBadRequest, fmt.Sprintf("invalid to: %v", err))
+			return
+		}
+	}
+
+	from := to.AddDate(0, 0, -(defaultStatsDays - 1))
+	if ctx.Query("from") != "" {
+		from, err = time.Parse(time.DateOnly, ctx.Query("from"))
+		if err != nil {
+			h.logger.Error(ctx, http.StatusBadRequest, err.Error())
+			newErrorResponse(ctx, http.StatusBadRequest, fmt.Sprintf("invalid from: %v", err))
+			return
+		}
+	}
+
+	stats, errResponse := h.statsService.GetBannerStats(ctx, bannerId, from, to)
+	if errResponse != nil {
+		h.newServiceErrorResponse(ctx, errResponse)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, stats)
+}
@@ -1,8 +1,11 @@
 package httpv1
 
 import (
+	"avito-test2024-spring/internal/models"
+	"encoding/json"
 	"errors"
 	"github.com/gin-gonic/gin"
+	"io"
 	"net/http"
 	"strconv"
 )
@@ -11,15 +14,23 @@ func (h *Handler) initTagsFeaturesRoutes(api *gin.RouterGroup) {
 	tags := api.Group("/tags", h.userIdentity)
 	{
 		tags.POST("/", h.addTag)
+		tags.PATCH("/:id", h.updateTag)
 		tags.DELETE("/:id", h.deleteTag)
 		tags.GET("/", h.getAllTags)
+		tags.GET("/:id", h.getTag)
+		tags.GET("/name/:name", h.getTagByName)
 	}
 
 	features := api.Group("/features", h.userIdentity)
 	{
 		features.POST("/", h.addFeature)
+		features.PATCH("/:id", h.updateFeature)
 		features.DELETE("/:id", h.deleteFeature)
 		features.GET("/", h.getAllFeatures)
+		features.GET("/:id", h.getFeature)
+		features.GET("/name/:name", h.getFeatureByName)
+		features.PUT("/:id/schema", h.setFeatureContentSchema)
+		features.GET("/:id/schema", h.getFeatureContentSchema)
 	}
 }
 
@@ -27,11 +38,15 @@ func (h *Handler) initTagsFeaturesRoutes(api *gin.RouterGroup) {
 // @Description Создание нового тэга
 // @Tags tag
 // @ID create-tag
+// @Accept json
 // @Security Bearer
 // @Param Authorization header string true "Bearer token for authentication"
+// @Param body body models.Metadata false "Имя, описание и команда-владелец тэга, имя должно быть уникальным"
 // @Success 201 {object} int "Тэг успешно создан"
+// @Failure 400 {object} errorResponse "Некорректные данные"
 // @Failure 401 {string} string "Пользователь не авторизован"
 // @Failure 403 {string} string "Пользователь не имеет доступа"
+// @Failure 409 {object} errorResponse "Имя уже занято"
 // @Failure 500 {object} errorResponse "Внутренняя ошибка сервера"
 // @Router /tags [post]
 func (h *Handler) addTag(ctx *gin.Context) {
@@ -42,16 +57,70 @@ func (h *Handler) addTag(ctx *gin.Context) {
 		return
 	}
 
-	tagId, err := h.tagsService.AddTag(ctx)
-	if err.Status != 0 {
-		h.logger.Error(ctx, err.Status, err.Error)
-		newErrorResponse(ctx, err.Status, err.Error)
+	var metadata models.Metadata
+	if err := decodeOptionalBody(ctx, &metadata); err != nil {
+		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
+		newErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tagId, err := h.tagsService.AddTag(ctx, metadata)
+	if err != nil {
+		h.newServiceErrorResponse(ctx, err)
 		return
 	}
 
 	ctx.JSON(http.StatusCreated, gin.H{"tag_id": tagId})
 }
 
+// @Summary Updates a tag
+// @Description Изменение имени, описания и команды-владельца тэга. Поля, отсутствующие в запросе, не изменяются.
+// @Tags tag
+// @ID update-tag
+// @Accept json
+// @Security Bearer
+// @Param Authorization header string true "Bearer token for authentication"
+// @Param id path integer true "Идентификатор тэга"
+// @Param body body models.MetadataUpdate true "Новые значения полей"
+// @Success 200 {string} string "OK"
+// @Failure 400 {object} errorResponse "Некорректные данные"
+// @Failure 401 {string} string "Пользователь не авторизован"
+// @Failure 403 {string} string "Пользователь не имеет доступа"
+// @Failure 404 {object} errorResponse "Тэг не найден"
+// @Failure 409 {object} errorResponse "Имя уже занято"
+// @Failure 500 {object} errorResponse "Внутренняя ошибка сервера"
+// @Router /tags/{id} [patch]
+func (h *Handler) updateTag(ctx *gin.Context) {
+	isAdmin := ctx.Value(userCtx).(bool)
+	if !isAdmin {
+		h.logger.Error(ctx, http.StatusForbidden, "Пользователь не имеет доступа")
+		newErrorResponse(ctx, http.StatusForbidden, "Пользователь не имеет доступа")
+		return
+	}
+
+	tagId, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
+		newErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var update models.MetadataUpdate
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&update); err != nil {
+		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
+		newErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	errResponse := h.tagsService.UpdateTag(ctx, tagId, update)
+	if errResponse != nil {
+		h.newServiceErrorResponse(ctx, errResponse)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
 // @Summary Deletes a tag
 // @Description Удаление тэга
 // @Tags tag
@@ -63,6 +132,7 @@ func (h *Handler) addTag(ctx *gin.Context) {
 // @Failure 400 {object} errorResponse "Некорректные данные"
 // @Failure 401 {string} string "Пользователь не авторизован"
 // @Failure 403 {string} string "Пользователь не имеет доступа"
+// @Failure 409 {object} errorResponse "Тэг используется пользователями"
 // @Failure 500 {object} errorResponse "Внутренняя ошибка сервера"
 // @Router /tags/{id} [delete]
 func (h *Handler) deleteTag(ctx *gin.Context) {
@@ -81,9 +151,8 @@ func (h *Handler) deleteTag(ctx *gin.Context) {
 	}
 
 	errResponse := h.tagsService.DeleteTag(ctx, tagId)
-	if errResponse.Status != 0 {
-		h.logger.Error(ctx, errResponse.Status, errResponse.Error)
-		newErrorResponse(ctx, errResponse.Status, errResponse.Error)
+	if errResponse != nil {
+		h.newServiceErrorResponse(ctx, errResponse)
 		return
 	}
 
@@ -96,9 +165,11 @@ func (h *Handler) deleteTag(ctx *gin.Context) {
 // @ID get-tags
 // @Security Bearer
 // @Param Authorization header string true "Bearer token for authentication"
-// @Param limit query integer false "Лимит"
-// @Param offset query integer false "Оффсет"
+// @Param limit query integer false "Размер страницы, по умолчанию pagination.defaultLimit, не больше pagination.maxLimit"
+// @Param cursor query string false "Курсор следующей страницы из заголовка X-Next-Cursor"
 // @Success 200 {array} models.Tag "OK"
+// @Header 200 {integer} X-Total-Count "Общее количество записей"
+// @Header 200 {string} X-Next-Cursor "Курсор следующей страницы, отсутствует на последней странице"
 // @Failure 401 {string} string "Пользователь не авторизован"
 // @Failure 403 {string} string "Пользователь не имеет доступа"
 // @Failure 500 {string} string "Внутренняя ошибка сервера"
@@ -111,47 +182,106 @@ func (h *Handler) getAllTags(ctx *gin.Context) {
 		return
 	}
 
-	limit, err := strconv.Atoi(ctx.Query("limit"))
-	if err != nil && errors.Is(err, strconv.ErrSyntax) && ctx.Query("limit") != "" {
+	page, err := parsePageRequest(ctx)
+	if err != nil {
 		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
 		newErrorResponse(ctx, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	if ctx.Query("limit") == "" {
-		limit = 0
+	tags, pageInfo, errResponse := h.tagsService.GetAllTags(ctx, page)
+	if errResponse != nil {
+		h.newServiceErrorResponse(ctx, errResponse)
+		return
 	}
 
-	offset, err := strconv.Atoi(ctx.Query("offset"))
-	if err != nil && errors.Is(err, strconv.ErrSyntax) && ctx.Query("offset") != "" {
+	setPageHeaders(ctx, pageInfo)
+
+	ctx.JSON(http.StatusOK, tags)
+}
+
+// @Summary Получение тэга
+// @Description Получение тэга с именем, описанием и командой-владельцем
+// @Tags tag
+// @ID get-tag
+// @Security Bearer
+// @Param Authorization header string true "Bearer token for authentication"
+// @Param id path integer true "Идентификатор тэга"
+// @Success 200 {object} models.Tag "OK"
+// @Failure 400 {object} errorResponse "Некорректные данные"
+// @Failure 401 {string} string "Пользователь не авторизован"
+// @Failure 403 {string} string "Пользователь не имеет доступа"
+// @Failure 404 {object} errorResponse "Тэг не найден"
+// @Failure 500 {object} errorResponse "Внутренняя ошибка сервера"
+// @Router /tags/{id} [get]
+func (h *Handler) getTag(ctx *gin.Context) {
+	isAdmin := ctx.Value(userCtx).(bool)
+	if !isAdmin {
+		h.logger.Error(ctx, http.StatusForbidden, "Пользователь не имеет доступа")
+		newErrorResponse(ctx, http.StatusForbidden, "Пользователь не имеет доступа")
+		return
+	}
+
+	tagId, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
 		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
 		newErrorResponse(ctx, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	if ctx.Query("offset") == "" {
-		offset = 0
+	tag, errResponse := h.tagsService.GetTag(ctx, tagId)
+	if errResponse != nil {
+		h.newServiceErrorResponse(ctx, errResponse)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, tag)
+}
+
+// @Summary Получение тэга по имени
+// @Description Получение тэга по уникальному имени
+// @Tags tag
+// @ID get-tag-by-name
+// @Security Bearer
+// @Param Authorization header string true "Bearer token for authentication"
+// @Param name path string true "Имя тэга"
+// @Success 200 {object} models.Tag "OK"
+// @Failure 400 {object} errorResponse "Некорректные данные"
+// @Failure 401 {string} string "Пользователь не авторизован"
+// @Failure 403 {string} string "Пользователь не имеет доступа"
+// @Failure 404 {object} errorResponse "Тэг не найден"
+// @Failure 500 {object} errorResponse "Внутренняя ошибка сервера"
+// @Router /tags/name/{name} [get]
+func (h *Handler) getTagByName(ctx *gin.Context) {
+	isAdmin := ctx.Value(userCtx).(bool)
+	if !isAdmin {
+		h.logger.Error(ctx, http.StatusForbidden, "Пользователь не имеет доступа")
+		newErrorResponse(ctx, http.StatusForbidden, "Пользователь не имеет доступа")
+		return
 	}
 
-	tags, errResponse := h.tagsService.GetAllTags(ctx, limit, offset)
-	if errResponse.Status != 0 {
-		h.logger.Error(ctx, errResponse.Status, errResponse.Error)
-		newErrorResponse(ctx, errResponse.Status, errResponse.Error)
+	tag, errResponse := h.tagsService.GetTagByName(ctx, ctx.Param("name"))
+	if errResponse != nil {
+		h.newServiceErrorResponse(ctx, errResponse)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, tags)
+	ctx.JSON(http.StatusOK, tag)
 }
 
 // @Summary Creates a new feature
 // @Description Создание новой фичи
 // @Tags feature
 // @ID create-feature
+// @Accept json
 // @Security Bearer
 // @Param Authorization header string true "Bearer token for authentication"
+// @Param body body models.Metadata false "Имя, описание и команда-владелец фичи, имя должно быть уникальным"
 // @Success 201 {object} int "Фича успешно создана"
+// @Failure 400 {object} errorResponse "Некорректные данные"
 // @Failure 401 {string} string "Пользователь не авторизован"
 // @Failure 403 {string} string "Пользователь не имеет доступа"
+// @Failure 409 {object} errorResponse "Имя уже занято"
 // @Failure 500 {object} errorResponse "Внутренняя ошибка сервера"
 // @Router /features [post]
 func (h *Handler) addFeature(ctx *gin.Context) {
@@ -162,16 +292,70 @@ func (h *Handler) addFeature(ctx *gin.Context) {
 		return
 	}
 
-	featureId, err := h.featuresService.AddFeature(ctx)
-	if err.Status != 0 {
-		h.logger.Error(ctx, err.Status, err.Error)
-		newErrorResponse(ctx, err.Status, err.Error)
+	var metadata models.Metadata
+	if err := decodeOptionalBody(ctx, &metadata); err != nil {
+		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
+		newErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	featureId, err := h.featuresService.AddFeature(ctx, metadata)
+	if err != nil {
+		h.newServiceErrorResponse(ctx, err)
 		return
 	}
 
 	ctx.JSON(http.StatusCreated, gin.H{"feature_id": featureId})
 }
 
+// @Summary Updates a feature
+// @Description Изменение имени, описания и команды-владельца фичи. Поля, отсутствующие в запросе, не изменяются.
+// @Tags feature
+// @ID update-feature
+// @Accept json
+// @Security Bearer
+// @Param Authorization header string true "Bearer token for authentication"
+// @Param id path integer true "Идентификатор фичи"
+// @Param body body models.MetadataUpdate true "Новые значения полей"
+// @Success 200 {string} string "OK"
+// @Failure 400 {object} errorResponse "Некорректные данные"
+// @Failure 401 {string} string "Пользователь не авторизован"
+// @Failure 403 {string} string "Пользователь не имеет доступа"
+// @Failure 404 {object} errorResponse "Фича не найдена"
+// @Failure 409 {object} errorResponse "Имя уже занято"
+// @Failure 500 {object} errorResponse "Внутренняя ошибка сервера"
+// @Router /features/{id} [patch]
+func (h *Handler) updateFeature(ctx *gin.Context) {
+	isAdmin := ctx.Value(userCtx).(bool)
+	if !isAdmin {
+		h.logger.Error(ctx, http.StatusForbidden, "Пользователь не имеет доступа")
+		newErrorResponse(ctx, http.StatusForbidden, "Пользователь не имеет доступа")
+		return
+	}
+
+	featureId, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
+		newErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var update models.MetadataUpdate
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&update); err != nil {
+		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
+		newErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	errResponse := h.featuresService.UpdateFeature(ctx, featureId, update)
+	if errResponse != nil {
+		h.newServiceErrorResponse(ctx, errResponse)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
 // @Summary Deletes a feature
 // @Description Удаление фичи
 // @Tags feature
@@ -201,9 +385,8 @@ func (h *Handler) deleteFeature(ctx *gin.Context) {
 	}
 
 	errResponse := h.featuresService.DeleteFeature(ctx, featureId)
-	if errResponse.Status != 0 {
-		h.logger.Error(ctx, errResponse.Status, errResponse.Error)
-		newErrorResponse(ctx, errResponse.Status, errResponse.Error)
+	if errResponse != nil {
+		h.newServiceErrorResponse(ctx, errResponse)
 		return
 	}
 
@@ -216,9 +399,11 @@ func (h *Handler) deleteFeature(ctx *gin.Context) {
 // @ID get-features
 // @Security Bearer
 // @Param Authorization header string true "Bearer token for authentication"
-// @Param limit query integer false "Лимит"
-// @Param offset query integer false "Оффсет"
+// @Param limit query integer false "Размер страницы, по умолчанию pagination.defaultLimit, не больше pagination.maxLimit"
+// @Param cursor query string false "Курсор следующей страницы из заголовка X-Next-Cursor"
 // @Success 200 {array} models.Feature "OK"
+// @Header 200 {integer} X-Total-Count "Общее количество записей"
+// @Header 200 {string} X-Next-Cursor "Курсор следующей страницы, отсутствует на последней странице"
 // @Failure 401 {string} string "Пользователь не авторизован"
 // @Failure 403 {string} string "Пользователь не имеет доступа"
 // @Failure 500 {string} string "Внутренняя ошибка сервера"
@@ -231,34 +416,189 @@ func (h *Handler) getAllFeatures(ctx *gin.Context) {
 		return
 	}
 
-	limit, err := strconv.Atoi(ctx.Query("limit"))
-	if err != nil && errors.Is(err, strconv.ErrSyntax) && ctx.Query("limit") != "" {
+	page, err := parsePageRequest(ctx)
+	if err != nil {
 		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
 		newErrorResponse(ctx, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	if ctx.Query("limit") == "" {
-		limit = 0
+	tags, pageInfo, errResponse := h.featuresService.GetAllFeatures(ctx, page)
+	if errResponse != nil {
+		h.newServiceErrorResponse(ctx, errResponse)
+		return
+	}
+
+	setPageHeaders(ctx, pageInfo)
+
+	ctx.JSON(http.StatusOK, tags)
+}
+
+// @Summary Получение фичи
+// @Description Получение фичи с именем, описанием и командой-владельцем
+// @Tags feature
+// @ID get-feature
+// @Security Bearer
+// @Param Authorization header string true "Bearer token for authentication"
+// @Param id path integer true "Идентификатор фичи"
+// @Success 200 {object} models.Feature "OK"
+// @Failure 400 {object} errorResponse "Некорректные данные"
+// @Failure 401 {string} string "Пользователь не авторизован"
+// @Failure 403 {string} string "Пользователь не имеет доступа"
+// @Failure 404 {object} errorResponse "Фича не найдена"
+// @Failure 500 {object} errorResponse "Внутренняя ошибка сервера"
+// @Router /features/{id} [get]
+func (h *Handler) getFeature(ctx *gin.Context) {
+	isAdmin := ctx.Value(userCtx).(bool)
+	if !isAdmin {
+		h.logger.Error(ctx, http.StatusForbidden, "Пользователь не имеет доступа")
+		newErrorResponse(ctx, http.StatusForbidden, "Пользователь не имеет доступа")
+		return
 	}
 
-	offset, err := strconv.Atoi(ctx.Query("offset"))
-	if err != nil && errors.Is(err, strconv.ErrSyntax) && ctx.Query("offset") != "" {
+	featureId, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
 		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
 		newErrorResponse(ctx, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	if ctx.Query("offset") == "" {
-		offset = 0
+	feature, errResponse := h.featuresService.GetFeature(ctx, featureId)
+	if errResponse != nil {
+		h.newServiceErrorResponse(ctx, errResponse)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, feature)
+}
+
+// @Summary Получение фичи по имени
+// @Description Получение фичи по уникальному имени
+// @Tags feature
+// @ID get-feature-by-name
+// @Security Bearer
+// @Param Authorization header string true "Bearer token for authentication"
+// @Param name path string true "Имя фичи"
+// @Success 200 {object} models.Feature "OK"
+// @Failure 400 {object} errorResponse "Некорректные данные"
+// @Failure 401 {string} string "Пользователь не авторизован"
+// @Failure 403 {string} string "Пользователь не имеет доступа"
+// @Failure 404 {object} errorResponse "Фича не найдена"
+// @Failure 500 {object} errorResponse "Внутренняя ошибка сервера"
+// @Router /features/name/{name} [get]
+func (h *Handler) getFeatureByName(ctx *gin.Context) {
+	isAdmin := ctx.Value(userCtx).(bool)
+	if !isAdmin {
+		h.logger.Error(ctx, http.StatusForbidden, "Пользователь не имеет доступа")
+		newErrorResponse(ctx, http.StatusForbidden, "Пользователь не имеет доступа")
+		return
 	}
 
-	tags, errResponse := h.featuresService.GetAllFeatures(ctx, limit, offset)
-	if errResponse.Status != 0 {
-		h.logger.Error(ctx, errResponse.Status, errResponse.Error)
-		newErrorResponse(ctx, errResponse.Status, errResponse.Error)
+	feature, errResponse := h.featuresService.GetFeatureByName(ctx, ctx.Param("name"))
+	if errResponse != nil {
+		h.newServiceErrorResponse(ctx, errResponse)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, tags)
+	ctx.JSON(http.StatusOK, feature)
+}
+
+// @Summary Установка JSON Schema содержимого баннеров фичи
+// @Description Этот эндпоинт задает JSON Schema, которой должно соответствовать содержимое баннеров фичи. Значение null удаляет схему.
+// @Tags feature
+// @ID set-feature-schema
+// @Accept json
+// @Security Bearer
+// @Param Authorization header string true "Bearer token for authentication"
+// @Param id path integer true "Идентификатор фичи"
+// @Param body body object true "JSON Schema содержимого баннера"
+// @Success 200 {string} string "OK"
+// @Failure 400 {object} errorResponse "Некорректные данные"
+// @Failure 401 {string} string "Пользователь не авторизован"
+// @Failure 403 {string} string "Пользователь не имеет доступа"
+// @Failure 404 {object} errorResponse "Фича не найдена"
+// @Failure 500 {object} errorResponse "Внутренняя ошибка сервера"
+// @Router /features/{id}/schema [put]
+func (h *Handler) setFeatureContentSchema(ctx *gin.Context) {
+	isAdmin := ctx.Value(userCtx).(bool)
+	if !isAdmin {
+		h.logger.Error(ctx, http.StatusForbidden, "Пользователь не имеет доступа")
+		newErrorResponse(ctx, http.StatusForbidden, "Пользователь не имеет доступа")
+		return
+	}
+
+	featureId, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
+		newErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var schema json.RawMessage
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&schema); err != nil {
+		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
+		newErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	errResponse := h.featuresService.SetContentSchema(ctx, featureId, schema)
+	if errResponse != nil {
+		h.newServiceErrorResponse(ctx, errResponse)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+// @Summary Получение JSON Schema содержимого баннеров фичи
+// @Description Получение JSON Schema содержимого баннеров фичи. Если схема не задана, возвращается null.
+// @Tags feature
+// @ID get-feature-schema
+// @Produce json
+// @Security Bearer
+// @Param Authorization header string true "Bearer token for authentication"
+// @Param id path integer true "Идентификатор фичи"
+// @Success 200 {object} object "OK"
+// @Failure 400 {object} errorResponse "Некорректные данные"
+// @Failure 401 {string} string "Пользователь не авторизован"
+// @Failure 403 {string} string "Пользователь не имеет доступа"
+// @Failure 404 {object} errorResponse "Фича не найдена"
+// @Failure 500 {object} errorResponse "Внутренняя ошибка сервера"
+// @Router /features/{id}/schema [get]
+func (h *Handler) getFeatureContentSchema(ctx *gin.Context) {
+	isAdmin := ctx.Value(userCtx).(bool)
+	if !isAdmin {
+		h.logger.Error(ctx, http.StatusForbidden, "Пользователь не имеет доступа")
+		newErrorResponse(ctx, http.StatusForbidden, "Пользователь не имеет доступа")
+		return
+	}
+
+	featureId, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
+		newErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	schema, errResponse := h.featuresService.GetContentSchema(ctx, featureId)
+	if errResponse != nil {
+		h.newServiceErrorResponse(ctx, errResponse)
+		return
+	}
+
+	if len(schema) == 0 {
+		schema = json.RawMessage("null")
+	}
+
+	ctx.Data(http.StatusOK, "application/json; charset=utf-8", schema)
+}
+
+// decodeOptionalBody decodes JSON body into v, empty body leaves v unchanged.
+func decodeOptionalBody(ctx *gin.Context, v any) error {
+	err := json.NewDecoder(ctx.Request.Body).Decode(v)
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+
+	return err
 }
@@ -0,0 +1,110 @@
+package httpv1
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type userBannersInput struct {
+	FeaturesIds     []int `json:"feature_ids" binding:"required"`
+	TagId           int   `json:"tag_id,omitempty"`
+	UseLastRevision bool  `json:"use_last_revision,omitempty"`
+}
+
+// @Summary Получение баннеров нескольких фич для пользователя
+// @Tags banner
+// @Description Этот эндпоинт возвращает баннеры пользователя для нескольких фич за один запрос.
+// @Description Баннер каждой фичи выбирается так же, как в /user_banner, фичи без баннера отсутствуют в ответе.
+// @ID get-user-banners
+// @Produce json
+// @Security Bearer
+// @Param Authorization header string true "Bearer token for authentication"
+// @Param feature_ids query []integer true "Идентификаторы фич через запятую или повторением параметра" collectionFormat(multi)
+// @Param tag_id query integer false "Explicit tag, any tag for admins and one of own tags for other users"
+// @Param use_last_revision query boolean false "Get the latest information" default(false)
+// @Success 200 {array} models.UserBanner "Баннеры найденных фич"
+// @Failure 400 {object} errorResponse "Некорректные данные"
+// @Failure 401 {object} errorResponse "Пользователь не авторизован"
+// @Failure 403 {object} errorResponse "Пользователь не имеет доступа"
+// @Failure 500 {object} errorResponse "Внутренняя ошибка сервера"
+// @Router /user_banners [get]
+func (h *Handler) getUserBanners(ctx *gin.Context) {
+	featuresIds, err := parseIdsQuery(ctx, "feature_ids")
+	if err != nil {
+		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
+		newErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tagId, err := parseOptionalTagId(ctx.Query("tag_id"))
+	if err != nil {
+		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
+		newErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	lastRevision, err := parseLastRevision(ctx.Query("use_last_revision"))
+	if err != nil {
+		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
+		newErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	h.respondUserBanners(ctx, userBannersInput{
+		FeaturesIds:     featuresIds,
+		TagId:           tagId,
+		UseLastRevision: lastRevision,
+	})
+}
+
+// @Summary Получение баннеров нескольких фич для пользователя
+// @Tags banner
+// @Description То же, что GET /user_banners, но параметры передаются в теле запроса, что удобно для длинных списков фич.
+// @ID post-user-banners
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param Authorization header string true "Bearer token for authentication"
+// @Param body body userBannersInput true "Фичи, явный тэг и use_last_revision"
+// @Success 200 {array} models.UserBanner "Баннеры найденных фич"
+// @Failure 400 {object} errorResponse "Некорректные данные"
+// @Failure 401 {object} errorResponse "Пользователь не авторизован"
+// @Failure 403 {object} errorResponse "Пользователь не имеет доступа"
+// @Failure 500 {object} errorResponse "Внутренняя ошибка сервера"
+// @Router /user_banners [post]
+func (h *Handler) postUserBanners(ctx *gin.Context) {
+	var input userBannersInput
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&input); err != nil {
+		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
+		newErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if input.TagId < 0 {
+		h.logger.Error(ctx, http.StatusBadRequest, "tag_id must be greater than 0")
+		newErrorResponse(ctx, http.StatusBadRequest, "tag_id must be greater than 0")
+		return
+	}
+
+	h.respondUserBanners(ctx, input)
+}
+
+func (h *Handler) respondUserBanners(ctx *gin.Context, input userBannersInput) {
+	userId := ctx.Value(userIdCtx).(int)
+
+	tagsIds, err := h.userBannerTags(ctx, userId, input.TagId)
+	if err != nil {
+		h.newServiceErrorResponse(ctx, err)
+		return
+	}
+
+	banners, errResponse := h.bannersService.GetUserBanners(ctx, input.FeaturesIds, tagsIds, userId,
+		input.UseLastRevision)
+	if errResponse != nil {
+		h.newServiceErrorResponse(ctx, errResponse)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, banners)
+}
@@ -11,7 +11,7 @@ import (
 )
 
 func (h *Handler) initUsersRoutes(api *gin.RouterGroup) {
-	adminsControlUser := api.Group("/users")
+	adminsControlUser := api.Group("/users", h.userIdentity)
 	{
 		adminsControlUser.POST("/", h.addUser)
 		adminsControlUser.GET("/", h.getAllUsers)
@@ -19,24 +19,52 @@ func (h *Handler) initUsersRoutes(api *gin.RouterGroup) {
 		adminsControlUser.DELETE("/:id", h.deleteUser)
 		adminsControlUser.PATCH("/:id", h.updateUser)
 	}
+
+	me := api.Group("/me", h.userIdentity)
+	{
+		me.GET("", h.getMe)
+	}
 }
 
 type UserInput struct {
 	IsAdmin bool `json:"is_admin" binding:"required"`
-	TagId   int  `json:"tag_id,omitempty"`
+	// TagsIds are ordered by priority, the first tag is the most important
+	TagsIds []int `json:"tag_ids,omitempty"`
+	// TagId is a single tag of the user, it is kept for clients which don't send tag_ids
+	TagId int `json:"tag_id,omitempty"`
+}
+
+// tags returns tags of the user from tag_ids or, if it is not set, from tag_id.
+func (i UserInput) tags() []int {
+	if len(i.TagsIds) == 0 && i.TagId != 0 {
+		return []int{i.TagId}
+	}
+
+	return i.TagsIds
 }
 
 // @Summary Creates a new user
 // @Description Создание нового пользователя
 // @Tags user
 // @ID create-user
+// @Security Bearer
+// @Param Authorization header string true "Bearer token for authentication"
 // @Accept json
 // @Param body body UserInput true "User creation request"
-// @Success 201 {string} string "Пользователь успешно создан"
+// @Success 201 {object} models.Tokens "Пользователь успешно создан"
 // @Failure 400 {object} errorResponse "Invalid data provided"
+// @Failure 401 {object} errorResponse "Пользователь не авторизован"
+// @Failure 403 {object} errorResponse "Пользователь не имеет доступа"
 // @Failure 500 {object} errorResponse "Внутренняя ошибка сервера"
 // @Router /users [post]
 func (h *Handler) addUser(ctx *gin.Context) {
+	isAdmin := ctx.Value(userCtx).(bool)
+	if !isAdmin {
+		h.logger.Error(ctx, http.StatusForbidden, "Пользователь не имеет доступа")
+		newErrorResponse(ctx, http.StatusForbidden, "Пользователь не имеет доступа")
+		return
+	}
+
 	var user UserInput
 	if err := json.NewDecoder(ctx.Request.Body).Decode(&user); err != nil {
 		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
@@ -44,32 +72,42 @@ func (h *Handler) addUser(ctx *gin.Context) {
 		return
 	}
 
-	accessToken, errResp := h.usersService.AddUser(ctx, service.UserAddInput{
-		TagId:   user.TagId,
+	tokens, errResp := h.usersService.AddUser(ctx, service.UserAddInput{
+		TagsIds: user.tags(),
 		IsAdmin: user.IsAdmin,
 	})
-	if errResp.Status != 0 {
-		h.logger.Error(ctx, errResp.Status, errResp.Error)
-		newErrorResponse(ctx, errResp.Status, errResp.Error)
+	if errResp != nil {
+		h.newServiceErrorResponse(ctx, errResp)
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, map[string]string{"access_token": accessToken})
+	ctx.JSON(http.StatusCreated, tokens)
 }
 
 // @Summary Обновление пользователя
 // @Description Этот эндпоинт предназначен для обновления пользователя по его идентификатору.
 // @Tags user
 // @ID update-user
+// @Security Bearer
+// @Param Authorization header string true "Bearer token for authentication"
 // @Accept json
 // @Param id path integer true "Идентификатор пользователя"
 // @Param body body UserInput true "Запрос на обновление пользователя"
 // @Success 200 {string} string "OK"
 // @Failure 400 {object} errorResponse "Некорректные данные"
 // @Failure 404 {string} string "Пользователь не найден"
+// @Failure 401 {object} errorResponse "Пользователь не авторизован"
+// @Failure 403 {object} errorResponse "Пользователь не имеет доступа"
 // @Failure 500 {object} errorResponse "Внутренняя ошибка сервера"
 // @Router /users/{id} [patch]
 func (h *Handler) updateUser(ctx *gin.Context) {
+	isAdmin := ctx.Value(userCtx).(bool)
+	if !isAdmin {
+		h.logger.Error(ctx, http.StatusForbidden, "Пользователь не имеет доступа")
+		newErrorResponse(ctx, http.StatusForbidden, "Пользователь не имеет доступа")
+		return
+	}
+
 	userId, err := strconv.Atoi(ctx.Param("id"))
 	if err != nil {
 		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
@@ -86,14 +124,13 @@ func (h *Handler) updateUser(ctx *gin.Context) {
 
 	user := models.User{
 		Id:      userId,
-		TagId:   userInput.TagId,
+		TagsIds: userInput.tags(),
 		IsAdmin: userInput.IsAdmin,
 	}
 
 	errResp := h.usersService.UpdateUser(ctx, user)
-	if errResp.Status != 0 {
-		h.logger.Error(ctx, errResp.Status, errResp.Error)
-		newErrorResponse(ctx, errResp.Status, errResp.Error)
+	if errResp != nil {
+		h.newServiceErrorResponse(ctx, errResp)
 		return
 	}
 
@@ -104,14 +141,25 @@ func (h *Handler) updateUser(ctx *gin.Context) {
 // @Description Этот эндпоинт предназначен для получения пользователя по его идентификатору.
 // @Tags user
 // @ID get-user
+// @Security Bearer
+// @Param Authorization header string true "Bearer token for authentication"
 // @Produce json
 // @Param id path integer true "Идентификатор пользователя"
 // @Success 200 {object} models.User "OK"
 // @Failure 400 {object} errorResponse "Некорректные данные"
 // @Failure 404 {string} string "Пользователь не найден"
+// @Failure 401 {object} errorResponse "Пользователь не авторизован"
+// @Failure 403 {object} errorResponse "Пользователь не имеет доступа"
 // @Failure 500 {object} errorResponse "Внутренняя ошибка сервера"
 // @Router /users/{id} [get]
 func (h *Handler) getUserById(ctx *gin.Context) {
+	isAdmin := ctx.Value(userCtx).(bool)
+	if !isAdmin {
+		h.logger.Error(ctx, http.StatusForbidden, "Пользователь не имеет доступа")
+		newErrorResponse(ctx, http.StatusForbidden, "Пользователь не имеет доступа")
+		return
+	}
+
 	userId, err := strconv.Atoi(ctx.Param("id"))
 	if err != nil {
 		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
@@ -120,67 +168,62 @@ func (h *Handler) getUserById(ctx *gin.Context) {
 	}
 
 	user, errResp := h.usersService.GetUserById(ctx, userId)
-	if errResp.Status != 0 {
-		h.logger.Error(ctx, errResp.Status, errResp.Error)
-		newErrorResponse(ctx, errResp.Status, errResp.Error)
+	if errResp != nil {
+		h.newServiceErrorResponse(ctx, errResp)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, user)
 }
 
-// @Summary Получение всех пользователей с фильтрацией по тегу
+// @Summary Получение всех пользователей с фильтрацией по тегам
 // @Tags user
-// @Description Этот эндпоинт предназначен для получения всех пользователей с фильтрацией по тегу
+// @Description Этот эндпоинт предназначен для получения всех пользователей с фильтрацией по тегам.
+// @Description Возвращаются пользователи, у которых есть хотя бы один из переданных тегов.
 // @ID get-users
+// @Security Bearer
+// @Param Authorization header string true "Bearer token for authentication"
 // @Produce json
-// @Param tag_id query integer false "Идентификатор тега"
-// @Param limit query integer false "Лимит"
-// @Param offset query integer false "Оффсет"
+// @Param tag_id query []integer false "Идентификаторы тегов через запятую или повторением параметра" collectionFormat(multi)
+// @Param limit query integer false "Размер страницы, по умолчанию pagination.defaultLimit, не больше pagination.maxLimit"
+// @Param cursor query string false "Курсор следующей страницы из заголовка X-Next-Cursor"
 // @Success 200 {array} models.User "OK"
+// @Header 200 {integer} X-Total-Count "Общее количество записей"
+// @Header 200 {string} X-Next-Cursor "Курсор следующей страницы, отсутствует на последней странице"
+// @Failure 401 {object} errorResponse "Пользователь не авторизован"
+// @Failure 403 {object} errorResponse "Пользователь не имеет доступа"
 // @Failure 500 {string} string "Внутренняя ошибка сервера"
 // @Router /users [get]
 func (h *Handler) getAllUsers(ctx *gin.Context) {
-	limit, err := strconv.Atoi(ctx.Query("limit"))
-	if err != nil && errors.Is(err, strconv.ErrSyntax) && ctx.Query("limit") != "" {
-		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
-		newErrorResponse(ctx, http.StatusBadRequest, err.Error())
+	isAdmin := ctx.Value(userCtx).(bool)
+	if !isAdmin {
+		h.logger.Error(ctx, http.StatusForbidden, "Пользователь не имеет доступа")
+		newErrorResponse(ctx, http.StatusForbidden, "Пользователь не имеет доступа")
 		return
 	}
 
-	if ctx.Query("limit") == "" {
-		limit = 0
-	}
-
-	offset, err := strconv.Atoi(ctx.Query("offset"))
-	if err != nil && errors.Is(err, strconv.ErrSyntax) && ctx.Query("offset") != "" {
+	page, err := parsePageRequest(ctx)
+	if err != nil {
 		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
 		newErrorResponse(ctx, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	if ctx.Query("offset") == "" {
-		offset = 0
-	}
-
-	tagId, err := strconv.Atoi(ctx.Query("tag_id"))
-	if err != nil && errors.Is(err, strconv.ErrSyntax) && ctx.Query("tag_id") != "" {
+	tagsIds, err := parseIdsQuery(ctx, "tag_id")
+	if err != nil {
 		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
 		newErrorResponse(ctx, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	if ctx.Query("tag_id") == "" {
-		tagId = 0
-	}
-
-	users, errResp := h.usersService.GetAllUsers(ctx, tagId, limit, offset)
-	if errResp.Status != 0 {
-		h.logger.Error(ctx, errResp.Status, errResp.Error)
-		newErrorResponse(ctx, errResp.Status, errResp.Error)
+	users, pageInfo, errResp := h.usersService.GetAllUsers(ctx, tagsIds, page)
+	if errResp != nil {
+		h.newServiceErrorResponse(ctx, errResp)
 		return
 	}
 
+	setPageHeaders(ctx, pageInfo)
+
 	ctx.JSON(http.StatusOK, users)
 }
 
@@ -188,12 +231,23 @@ func (h *Handler) getAllUsers(ctx *gin.Context) {
 // @Description Этот эндпоинт предназначен для удаления пользователя по его идентификатору.
 // @Tags user
 // @ID delete-user
+// @Security Bearer
+// @Param Authorization header string true "Bearer token for authentication"
 // @Param id path integer true "Идентификатор пользователя"
 // @Success 204 {string} string "Пользователь успешно удален"
 // @Failure 404 {string} string "Пользователь не найден"
+// @Failure 401 {object} errorResponse "Пользователь не авторизован"
+// @Failure 403 {object} errorResponse "Пользователь не имеет доступа"
 // @Failure 500 {object} errorResponse "Внутренняя ошибка сервера"
 // @Router /users/{id} [delete]
 func (h *Handler) deleteUser(ctx *gin.Context) {
+	isAdmin := ctx.Value(userCtx).(bool)
+	if !isAdmin {
+		h.logger.Error(ctx, http.StatusForbidden, "Пользователь не имеет доступа")
+		newErrorResponse(ctx, http.StatusForbidden, "Пользователь не имеет доступа")
+		return
+	}
+
 	userId, err := strconv.Atoi(ctx.Param("id"))
 	if err != nil {
 		h.logger.Error(ctx, http.StatusBadRequest, err.Error())
@@ -202,11 +256,37 @@ func (h *Handler) deleteUser(ctx *gin.Context) {
 	}
 
 	errResp := h.usersService.DeleteUser(ctx, userId)
-	if errResp.Status != 0 {
-		h.logger.Error(ctx, errResp.Status, errResp.Error)
-		newErrorResponse(ctx, errResp.Status, errResp.Error)
+	if errResp != nil {
+		h.newServiceErrorResponse(ctx, errResp)
 		return
 	}
 
 	ctx.Status(http.StatusNoContent)
 }
+
+// @Summary Получение профиля текущего пользователя
+// @Description Этот эндпоинт предназначен для получения пользователем своего профиля.
+// @Tags user
+// @ID get-me
+// @Produce json
+// @Security Bearer
+// @Param Authorization header string true "Bearer token for authentication"
+// @Success 200 {object} models.User "OK"
+// @Failure 401 {object} errorResponse "Пользователь не авторизован"
+// @Failure 500 {object} errorResponse "Внутренняя ошибка сервера"
+// @Router /me [get]
+func (h *Handler) getMe(ctx *gin.Context) {
+	userId := ctx.Value(userIdCtx).(int)
+
+	user, errResp := h.usersService.GetUserById(ctx, userId)
+	if errResp != nil {
+		if errors.Is(errResp, models.ErrNotFound) {
+			errResp = models.NewUnauthorizedError(errResp.Error())
+		}
+
+		h.newServiceErrorResponse(ctx, errResp)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, user)
+}
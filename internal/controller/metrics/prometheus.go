@@ -1,10 +1,13 @@
 package metrics
 
 import (
+	metrics2 "avito-test2024-spring/pkg/metrics"
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -24,11 +27,22 @@ var (
 		},
 		[]string{"status"},
 	)
+
+	// bannerServes counts user banners served successfully per feature
+	bannerServes = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "user_banner_serves_total",
+			Help: "Number of user banners served per feature",
+		},
+		[]string{"feature_id"},
+	)
 )
 
 func Init() {
 	prometheus.MustRegister(requestDuration)
 	prometheus.MustRegister(responseStatus)
+	prometheus.MustRegister(bannerServes)
+	metrics2.Register()
 }
 
 func PrometheusMiddleware() gin.HandlerFunc {
@@ -43,6 +57,14 @@ func PrometheusMiddleware() gin.HandlerFunc {
 
 		statusCode := c.Writer.Status()
 		responseStatus.WithLabelValues(strconv.Itoa(statusCode)).Inc()
+
+		// only successful and not modified responses are counted, so label values are limited to existing features
+		if (statusCode == http.StatusOK || statusCode == http.StatusNotModified) &&
+			strings.HasSuffix(c.FullPath(), "/user_banner") {
+			if featureId, err := strconv.Atoi(c.Query("feature_id")); err == nil {
+				bannerServes.WithLabelValues(strconv.Itoa(featureId)).Inc()
+			}
+		}
 	}
 }
 
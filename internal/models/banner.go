@@ -1,19 +1,28 @@
 package models
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"regexp"
+	"github.com/santhosh-tekuri/jsonschema/v5"
 	"slices"
+	"strings"
 	"time"
+	"unicode/utf8"
 )
 
-var regexURL = regexp.MustCompile(`^(http:\/\/www\.|https:\/\/www\.|http:\/\/|https:\/\/|\/|\/\/)?[A-z0-9_-]*?[:]?[A-z0-9_-]*?[@]?[A-z0-9]+([\-\.]{1}[a-z0-9]+)*\.[a-z]{2,5}(:[0-9]{1,5})?(\/.*)?$`)
+// Banner is a JSON document of undefined structure. It is stored and returned as is.
+type Banner = json.RawMessage
 
-type Banner struct {
-	Title string `json:"title"`
-	Text  string `json:"text"`
-	URL   string `json:"url"`
+const maxBannerSearchLength = 256
+
+// BannerETag returns strong entity tag of banner content for conditional requests.
+func BannerETag(content Banner) string {
+	sum := sha256.Sum256(content)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
 }
 
 type AdminBanner struct {
@@ -24,48 +33,318 @@ type AdminBanner struct {
 
 	IsActive bool `json:"is_active"`
 
+	// banner is shown to users only inside the window, nil bound means that window is not limited from that side
+	ActiveFrom  *time.Time `json:"active_from,omitempty"`
+	ActiveUntil *time.Time `json:"active_until,omitempty"`
+
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// Statuses of banner activation window used to filter banners list.
+const (
+	BannerWindowScheduled = "scheduled"
+	BannerWindowLive      = "live"
+	BannerWindowExpired   = "expired"
+)
+
+// Fields banners list can be sorted by.
+const (
+	BannerSortId        = "id"
+	BannerSortCreatedAt = "created_at"
+	BannerSortUpdatedAt = "updated_at"
+)
+
+// BannerFilter selects banners for admin list. Zero values of fields don't filter.
+type BannerFilter struct {
+	FeaturesIds []int
+	TagsIds     []int
+	IsActive    *bool
+	// Status is one of BannerWindow* statuses of activation window
+	Status string
+
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	UpdatedFrom *time.Time
+	UpdatedTo   *time.Time
+
+	// Search is a full-text query over title and text of banner content
+	Search string
+
+	// SortBy is one of BannerSort* fields, banners are sorted by id if it is empty
+	SortBy   string
+	SortDesc bool
+}
+
+// Validate checks filter and sets the default sort.
+func (f *BannerFilter) Validate() error {
+	for _, id := range f.FeaturesIds {
+		if id <= 0 {
+			return errors.New("feature_id must be greater than 0")
+		}
+	}
+
+	for _, id := range f.TagsIds {
+		if id <= 0 {
+			return errors.New("tag_id must be greater than 0")
+		}
+	}
+
+	if f.Status != "" && f.Status != BannerWindowScheduled && f.Status != BannerWindowLive &&
+		f.Status != BannerWindowExpired {
+		return errors.New("status must be one of scheduled, live or expired")
+	}
+
+	if f.CreatedFrom != nil && f.CreatedTo != nil && f.CreatedTo.Before(*f.CreatedFrom) {
+		return errors.New("created_from must be before created_to")
+	}
+
+	if f.UpdatedFrom != nil && f.UpdatedTo != nil && f.UpdatedTo.Before(*f.UpdatedFrom) {
+		return errors.New("updated_from must be before updated_to")
+	}
+
+	if utf8.RuneCountInString(f.Search) > maxBannerSearchLength {
+		return fmt.Errorf("search must be at most %v characters", maxBannerSearchLength)
+	}
+
+	switch f.SortBy {
+	case "":
+		f.SortBy = BannerSortId
+	case BannerSortId, BannerSortCreatedAt, BannerSortUpdatedAt:
+	default:
+		return errors.New("sort must be one of id, created_at or updated_at")
+	}
+
+	return nil
+}
+
+// Cursor returns position of banner in the list sorted according to filter.
+func (f *BannerFilter) Cursor(banner AdminBanner) Cursor {
+	c := Cursor{AfterId: banner.ID, Sort: f.SortBy, Desc: f.SortDesc}
+
+	switch f.SortBy {
+	case BannerSortCreatedAt:
+		c.AfterTime = banner.CreatedAt
+	case BannerSortUpdatedAt:
+		c.AfterTime = banner.UpdatedAt
+	}
+
+	return c
+}
+
+// CheckCursor checks that cursor was made for the same sort as the filter has.
+func (f *BannerFilter) CheckCursor(c Cursor) error {
+	if c.IsZero() {
+		return nil
+	}
+
+	sort := c.Sort
+	if sort == "" {
+		sort = BannerSortId
+	}
+
+	if sort != f.SortBy || c.Desc != f.SortDesc {
+		return errors.New("cursor was made for another sort")
+	}
+
+	return nil
+}
+
+type BannerVersion struct {
+	Version  int     `json:"version"`
+	Content  Banner  `json:"content"`
+	Tags     []Tag   `json:"tags_ids"`
+	Feature  Feature `json:"feature_id"`
+	IsActive bool    `json:"is_active"`
+
+	ActiveFrom  *time.Time `json:"active_from,omitempty"`
+	ActiveUntil *time.Time `json:"active_until,omitempty"`
+
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// UserBanner is a banner of feature chosen for the user in batch request.
+type UserBanner struct {
+	FeatureId int `json:"feature_id"`
+	TagId     int `json:"tag_id"`
+	// Variant is banner id of A/B experiment variant, 0 if there is no experiment
+	Variant int    `json:"variant,omitempty"`
+	Content Banner `json:"content" swaggertype:"object"`
+
+	// ETag and ExpiresAt of the cached banner are used for conditional requests of a single banner
+	ETag      string    `json:"-"`
+	ExpiresAt time.Time `json:"-"`
+}
+
+// BannerConflict describes a pair of tag and feature which is already taken by another banner.
+type BannerConflict struct {
+	TagId     int `json:"tag_id"`
+	FeatureId int `json:"feature_id"`
+	BannerId  int `json:"banner_id"`
+}
+
 type Feature struct {
 	ID int `json:"feature_id"`
+	Metadata
+
+	ContentSchema json.RawMessage `json:"content_schema,omitempty"`
 }
 
 type Tag struct {
 	ID int `json:"tag_id"`
+	Metadata
+}
+
+// Metadata describes tag or feature for admins. It is not loaded for tags and features of banners.
+type Metadata struct {
+	// Name is unique among tags or among features, empty name is not set
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	OwnerTeam   string `json:"owner_team,omitempty"`
+}
+
+// MetadataUpdate is a partial update of Metadata, nil fields are left unchanged.
+type MetadataUpdate struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	OwnerTeam   *string `json:"owner_team"`
+}
+
+// Apply sets fields of m which are present in the update.
+func (u MetadataUpdate) Apply(m *Metadata) {
+	if u.Name != nil {
+		m.Name = *u.Name
+	}
+
+	if u.Description != nil {
+		m.Description = *u.Description
+	}
+
+	if u.OwnerTeam != nil {
+		m.OwnerTeam = *u.OwnerTeam
+	}
 }
 
-func (b *Banner) ValidateBanner() error {
-	if len(b.Title) == 0 {
-		return errors.New(fmt.Sprintf("title is empty"))
+const (
+	maxMetadataNameLength      = 255
+	maxMetadataOwnerTeamLength = 255
+)
+
+// Normalize trims spaces around fields and validates them.
+func (m *Metadata) Normalize() error {
+	m.Name = strings.TrimSpace(m.Name)
+	m.Description = strings.TrimSpace(m.Description)
+	m.OwnerTeam = strings.TrimSpace(m.OwnerTeam)
+
+	// varchar limits characters, not bytes
+	if utf8.RuneCountInString(m.Name) > maxMetadataNameLength {
+		return fmt.Errorf("name must be at most %v characters", maxMetadataNameLength)
 	}
 
-	if len(b.Title) > 255 {
-		return errors.New(fmt.Sprintf("title length is too long."+
-			" it must be less than %v, but have %v", 255, len(b.Title)))
+	if utf8.RuneCountInString(m.OwnerTeam) > maxMetadataOwnerTeamLength {
+		return fmt.Errorf("owner_team must be at most %v characters", maxMetadataOwnerTeamLength)
 	}
 
-	if len(b.Text) == 0 {
-		return errors.New(fmt.Sprintf("text is empty"))
+	return nil
+}
+
+func ValidateBannerContent(content Banner) error {
+	if len(content) == 0 {
+		return errors.New("content is empty")
 	}
 
-	if len(b.Text) > 1000 {
-		return errors.New(fmt.Sprintf("text length is too long."+
-			" it must be less than %v, but have %v", 255, len(b.Text)))
+	var v interface{}
+	if err := json.Unmarshal(content, &v); err != nil {
+		return errors.New(fmt.Sprintf("content is not a valid json: %v", err))
 	}
 
-	if len(b.URL) == 0 {
-		return errors.New(fmt.Sprintf("url is empty"))
+	if _, ok := v.(map[string]interface{}); !ok {
+		return errors.New("content must be a json object")
 	}
 
-	if !regexURL.MatchString(b.URL) {
-		return errors.New("url is incorrect")
+	return nil
+}
+
+// ValidateContentSchema checks that schema is a valid JSON Schema document.
+func ValidateContentSchema(schema json.RawMessage) error {
+	_, err := compileContentSchema(schema)
+	if err != nil {
+		return errors.New(fmt.Sprintf("content_schema is invalid: %v", err))
 	}
 
 	return nil
 }
 
+// ValidateBannerContentBySchema checks banner content against JSON Schema of its feature.
+// Empty schema means that feature has no restrictions on content.
+func ValidateBannerContentBySchema(content Banner, schema json.RawMessage) error {
+	if len(schema) == 0 {
+		return nil
+	}
+
+	compiled, err := compileContentSchema(schema)
+	if err != nil {
+		return errors.New(fmt.Sprintf("content_schema is invalid: %v", err))
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(content, &v); err != nil {
+		return err
+	}
+
+	if err := compiled.Validate(v); err != nil {
+		return errors.New(fmt.Sprintf("content doesn't match feature content_schema: %v", err))
+	}
+
+	return nil
+}
+
+func compileContentSchema(schema json.RawMessage) (*jsonschema.Schema, error) {
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource("content_schema.json", bytes.NewReader(schema)); err != nil {
+		return nil, err
+	}
+
+	return compiler.Compile("content_schema.json")
+}
+
+func (b *AdminBanner) ValidateAndSetActiveWindow(activeFrom *time.Time, activeUntil *time.Time) error {
+	if activeFrom != nil && activeUntil != nil && !activeUntil.After(*activeFrom) {
+		return errors.New("active_until must be after active_from")
+	}
+
+	b.ActiveFrom = activeFrom
+	b.ActiveUntil = activeUntil
+
+	return nil
+}
+
+// IsLive reports whether banner activation window contains t.
+func (b *AdminBanner) IsLive(t time.Time) bool {
+	if b.ActiveFrom != nil && b.ActiveFrom.After(t) {
+		return false
+	}
+
+	if b.ActiveUntil != nil && !b.ActiveUntil.After(t) {
+		return false
+	}
+
+	return true
+}
+
+// SameActiveWindow reports whether banners have the same activation window.
+func (b *AdminBanner) SameActiveWindow(other AdminBanner) bool {
+	return sameTime(b.ActiveFrom, other.ActiveFrom) && sameTime(b.ActiveUntil, other.ActiveUntil)
+}
+
+func sameTime(a *time.Time, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return a.Equal(*b)
+}
+
 func (b *AdminBanner) ValidateAndSetTags(tags []int) error {
 	if len(slices.Compact(tags)) != len(tags) {
 		return errors.New("list of tags_ids contain similar ids")
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBannerFilter(t *testing.T) {
+	t.Run("Default_Sort", func(t *testing.T) {
+		filter := BannerFilter{FeaturesIds: []int{1, 2}, TagsIds: []int{3}}
+		require.NoError(t, filter.Validate())
+		require.Equal(t, BannerSortId, filter.SortBy)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		from := time.Now()
+		to := from.Add(-time.Hour)
+
+		for _, filter := range []BannerFilter{
+			{FeaturesIds: []int{0}},
+			{TagsIds: []int{-1}},
+			{Status: "unknown"},
+			{SortBy: "content"},
+			{CreatedFrom: &from, CreatedTo: &to},
+		} {
+			require.Error(t, filter.Validate())
+		}
+	})
+
+	t.Run("Search_Length", func(t *testing.T) {
+		// limit is in characters, cyrillic ones take two bytes
+		filter := BannerFilter{Search: strings.Repeat("я", maxBannerSearchLength)}
+		require.NoError(t, filter.Validate())
+
+		filter = BannerFilter{Search: strings.Repeat("я", maxBannerSearchLength+1)}
+		require.Error(t, filter.Validate())
+	})
+
+	t.Run("Cursor", func(t *testing.T) {
+		filter := BannerFilter{SortBy: BannerSortUpdatedAt, SortDesc: true}
+		banner := AdminBanner{ID: 7, UpdatedAt: time.Now()}
+
+		cursor := filter.Cursor(banner)
+		require.Equal(t, banner.UpdatedAt, cursor.AfterTime)
+		require.NoError(t, filter.CheckCursor(cursor))
+
+		other := BannerFilter{SortBy: BannerSortId}
+		require.Error(t, other.CheckCursor(cursor))
+		require.NoError(t, other.CheckCursor(Cursor{AfterId: 7}))
+	})
+}
+
+func TestMetadata_Normalize(t *testing.T) {
+	m := Metadata{Name: "  " + strings.Repeat("я", maxMetadataNameLength) + " ", OwnerTeam: "команда"}
+	require.NoError(t, m.Normalize(), "length is limited in characters, not bytes")
+	require.Equal(t, strings.Repeat("я", maxMetadataNameLength), m.Name)
+
+	m.Name += "я"
+	require.Error(t, m.Normalize())
+}
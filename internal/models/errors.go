@@ -1,13 +1,62 @@
 package models
 
-type ErrService struct {
-	Status int
-	Error  string
+import (
+	"errors"
+	"fmt"
+)
+
+// Kinds of domain errors. Use errors.Is to check the kind of error returned by services and repositories.
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrValidation   = errors.New("validation failed")
+	ErrForbidden    = errors.New("forbidden")
+	ErrUnauthorized = errors.New("unauthorized")
+)
+
+// Error is a domain error with message for the client and one of the kinds above.
+type Error struct {
+	Kind    error
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+func NewNotFoundError(message string) error {
+	return &Error{Kind: ErrNotFound, Message: message}
+}
+
+func NewConflictError(message string) error {
+	return &Error{Kind: ErrConflict, Message: message}
+}
+
+func NewValidationError(message string) error {
+	return &Error{Kind: ErrValidation, Message: message}
+}
+
+func NewForbiddenError(message string) error {
+	return &Error{Kind: ErrForbidden, Message: message}
+}
+
+func NewUnauthorizedError(message string) error {
+	return &Error{Kind: ErrUnauthorized, Message: message}
+}
+
+// BannerConflictsError is a conflict error with the list of tag and feature pairs taken by other banners.
+type BannerConflictsError struct {
+	Conflicts []BannerConflict
+}
+
+func (e *BannerConflictsError) Error() string {
+	return fmt.Sprintf("%v pairs of feature_id and tag_id are already taken by other banners", len(e.Conflicts))
 }
 
-func NewErrorService(status int, err string) ErrService {
-	return ErrService{
-		Status: status,
-		Error:  err,
-	}
+func (e *BannerConflictsError) Unwrap() error {
+	return ErrConflict
 }
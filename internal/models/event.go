@@ -0,0 +1,93 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// Types of banner change events.
+const (
+	BannerEventCreated = "created"
+	BannerEventUpdated = "updated"
+	BannerEventToggled = "toggled"
+	BannerEventDeleted = "deleted"
+)
+
+// BannerEvent tells clients that banners of features and tags have changed and should be fetched again.
+// When banner is moved to other feature or tags, both old and new ones are listed. Event about several banners,
+// e.g. deleted by one batch, has zero BannerId and lists them in BannersIds.
+type BannerEvent struct {
+	Type        string    `json:"type"`
+	BannerId    int       `json:"banner_id"`
+	BannersIds  []int     `json:"banner_ids,omitempty"`
+	FeaturesIds []int     `json:"feature_ids"`
+	TagsIds     []int     `json:"tag_ids"`
+	At          time.Time `json:"at"`
+}
+
+// NewBannerEvent returns event about banners, their features and tags are merged without repeats.
+func NewBannerEvent(eventType string, bannerId int, banners ...AdminBanner) BannerEvent {
+	event := BannerEvent{
+		Type:        eventType,
+		BannerId:    bannerId,
+		FeaturesIds: make([]int, 0, len(banners)),
+		TagsIds:     make([]int, 0),
+		At:          time.Now().UTC(),
+	}
+
+	for _, banner := range banners {
+		if banner.Feature.ID != 0 && !slices.Contains(event.FeaturesIds, banner.Feature.ID) {
+			event.FeaturesIds = append(event.FeaturesIds, banner.Feature.ID)
+		}
+
+		for _, tag := range banner.Tags {
+			if !slices.Contains(event.TagsIds, tag.ID) {
+				event.TagsIds = append(event.TagsIds, tag.ID)
+			}
+		}
+	}
+
+	return event
+}
+
+// NewBannersEvent returns event about several banners, like NewBannerEvent does for one.
+func NewBannersEvent(eventType string, banners []AdminBanner) BannerEvent {
+	event := NewBannerEvent(eventType, 0, banners...)
+
+	event.BannersIds = make([]int, 0, len(banners))
+	for _, banner := range banners {
+		event.BannersIds = append(event.BannersIds, banner.ID)
+	}
+
+	return event
+}
+
+// NewFeatureTagEvent returns event about banner served for the pair of feature and tag, e.g. when A/B experiment
+// on the pair is changed and users may get other banner.
+func NewFeatureTagEvent(eventType string, featureId int, tagId int) BannerEvent {
+	return NewBannerEvent(eventType, 0, AdminBanner{Feature: Feature{ID: featureId}, Tags: []Tag{{ID: tagId}}})
+}
+
+// BannerEventFilter selects events of subscriber, zero FeatureId and empty TagsIds match any feature and tag.
+type BannerEventFilter struct {
+	FeatureId int
+	TagsIds   []int
+}
+
+func (f BannerEventFilter) Matches(event BannerEvent) bool {
+	if f.FeatureId != 0 && !slices.Contains(event.FeaturesIds, f.FeatureId) {
+		return false
+	}
+
+	if len(f.TagsIds) == 0 {
+		return true
+	}
+
+	for _, tagId := range f.TagsIds {
+		if slices.Contains(event.TagsIds, tagId) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestBannerEventFilter(t *testing.T) {
+	event := NewBannerEvent(BannerEventUpdated, 1,
+		AdminBanner{ID: 1, Feature: Feature{ID: 10}, Tags: []Tag{{ID: 1}, {ID: 2}}},
+		AdminBanner{ID: 1, Feature: Feature{ID: 20}, Tags: []Tag{{ID: 2}, {ID: 3}}},
+	)
+	require.Equal(t, []int{10, 20}, event.FeaturesIds)
+	require.Equal(t, []int{1, 2, 3}, event.TagsIds)
+
+	require.True(t, BannerEventFilter{}.Matches(event))
+	require.True(t, BannerEventFilter{FeatureId: 20}.Matches(event))
+	require.True(t, BannerEventFilter{FeatureId: 10, TagsIds: []int{5, 3}}.Matches(event))
+
+	require.False(t, BannerEventFilter{FeatureId: 30}.Matches(event))
+	require.False(t, BannerEventFilter{TagsIds: []int{4, 5}}.Matches(event))
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+const (
+	JobStatusPending = "pending"
+	JobStatusRunning = "running"
+	JobStatusDone    = "done"
+	JobStatusFailed  = "failed"
+)
+
+type Job struct {
+	ID        int    `json:"job_id"`
+	FeatureId int    `json:"feature_id"`
+	TagId     int    `json:"tag_id"`
+	Status    string `json:"status"`
+	Total     int    `json:"total"`
+	Processed int    `json:"processed"`
+	Error     string `json:"error,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
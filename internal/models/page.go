@@ -0,0 +1,62 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// PageRequest selects a page of list. Cursor is empty for the first page and is taken from
+// PageInfo.NextCursor for the next ones. Zero Limit means the default page size.
+type PageRequest struct {
+	Cursor string
+	Limit  int
+}
+
+// PageInfo describes returned page. NextCursor is empty on the last page,
+// Total is the number of items matching filters on all pages.
+type PageInfo struct {
+	NextCursor string
+	Total      int
+}
+
+// Cursor is the position of the last item of previous page. Lists sorted by id use only AfterId,
+// lists sorted by other field also keep its value and the sort, so cursor can't be used with another sort.
+type Cursor struct {
+	AfterId   int       `json:"after_id"`
+	AfterTime time.Time `json:"after_time"`
+	Sort      string    `json:"sort,omitempty"`
+	Desc      bool      `json:"desc,omitempty"`
+}
+
+// IsZero reports whether cursor points to the first page.
+func (c Cursor) IsZero() bool {
+	return c.AfterId == 0
+}
+
+// EncodeCursor makes opaque token of cursor.
+func EncodeCursor(c Cursor) string {
+	data, _ := json.Marshal(c)
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses token made by EncodeCursor, empty token means the first page.
+func DecodeCursor(token string) (Cursor, error) {
+	if token == "" {
+		return Cursor{}, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return Cursor{}, errors.New("invalid cursor")
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.AfterId <= 0 {
+		return Cursor{}, errors.New("invalid cursor")
+	}
+
+	return c, nil
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestCursor(t *testing.T) {
+	c := Cursor{AfterId: 42, AfterTime: time.Date(2024, 4, 10, 12, 0, 0, 123456000, time.UTC), Sort: "created_at", Desc: true}
+
+	decoded, err := DecodeCursor(EncodeCursor(c))
+	require.NoError(t, err)
+	require.Equal(t, c, decoded)
+
+	t.Run("Empty", func(t *testing.T) {
+		decoded, err := DecodeCursor("")
+		require.NoError(t, err)
+		require.True(t, decoded.IsZero())
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := DecodeCursor("not a cursor")
+		require.Error(t, err)
+
+		_, err = DecodeCursor(EncodeCursor(Cursor{AfterId: -1}))
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,20 @@
+package models
+
+// BannerStats is the number of impressions and clicks of banner for one day (UTC).
+type BannerStats struct {
+	BannerId    int     `json:"banner_id"`
+	Day         string  `json:"day"`
+	Impressions int     `json:"impressions"`
+	Clicks      int     `json:"clicks"`
+	CTR         float64 `json:"ctr"`
+}
+
+// SetCTR calculates click-through rate, which is 0 for banners without impressions.
+func (s *BannerStats) SetCTR() {
+	if s.Impressions == 0 {
+		s.CTR = 0
+		return
+	}
+
+	s.CTR = float64(s.Clicks) / float64(s.Impressions)
+}
@@ -1,7 +1,43 @@
 package models
 
+import (
+	"fmt"
+	"time"
+)
+
 type User struct {
-	Id      int  `json:"id"`
-	TagId   int  `json:"tag_id"`
-	IsAdmin bool `json:"is_admin"`
+	Id int `json:"id"`
+	// TagsIds are ordered by priority, banner of the first tag which has one is shown to the user
+	TagsIds []int `json:"tag_ids"`
+	IsAdmin bool  `json:"is_admin"`
+}
+
+// ValidateTags checks that tags of the user are positive and not repeated, so their priorities are unambiguous.
+func (u User) ValidateTags() error {
+	seen := make(map[int]struct{}, len(u.TagsIds))
+	for _, tagId := range u.TagsIds {
+		if tagId <= 0 {
+			return fmt.Errorf("users tag_ids must be greater than 0")
+		}
+
+		if _, ok := seen[tagId]; ok {
+			return fmt.Errorf("users tag_ids contain tag_id=%v twice", tagId)
+		}
+		seen[tagId] = struct{}{}
+	}
+
+	return nil
+}
+
+type Session struct {
+	ID           int
+	UserId       int
+	RefreshToken string
+	ExpiresAt    time.Time
+	CreatedAt    time.Time
+}
+
+type Tokens struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
 }
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestUser_ValidateTags(t *testing.T) {
+	require.NoError(t, User{}.ValidateTags())
+	require.NoError(t, User{TagsIds: []int{3, 1, 2}}.ValidateTags())
+
+	t.Run("Invalid", func(t *testing.T) {
+		require.Error(t, User{TagsIds: []int{0}}.ValidateTags())
+		require.Error(t, User{TagsIds: []int{1, -2}}.ValidateTags())
+		require.Error(t, User{TagsIds: []int{1, 2, 1}}.ValidateTags())
+	})
+}
@@ -0,0 +1,114 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+)
+
+// Variant is a banner taking part in A/B experiment on a pair of feature and tag.
+// Users get variants proportionally to their weights.
+type Variant struct {
+	BannerId int `json:"banner_id"`
+	Weight   int `json:"weight"`
+	// Live is set for variants whose banner is active and inside activation window
+	Live bool `json:"-"`
+}
+
+func ValidateVariants(variants []Variant) error {
+	if len(variants) == 0 {
+		return errors.New("variants must not be empty")
+	}
+
+	seen := make(map[int]struct{}, len(variants))
+	for _, v := range variants {
+		if v.BannerId <= 0 {
+			return errors.New("banner_id of variant must be greater than 0")
+		}
+
+		if v.Weight <= 0 {
+			return errors.New("weight of variant must be greater than 0")
+		}
+
+		if _, ok := seen[v.BannerId]; ok {
+			return fmt.Errorf("banner_id=%v is used in several variants", v.BannerId)
+		}
+		seen[v.BannerId] = struct{}{}
+	}
+
+	return nil
+}
+
+// ValidateVariantBanner checks that banner can be a variant of experiment on feature and tag. It must belong to
+// the feature and either have the tag or have no tags: only one banner can have the pair of feature and tag,
+// so other variants are banners of the feature without tags, which are not shown outside of experiments.
+func ValidateVariantBanner(banner AdminBanner, featureId int, tagId int) error {
+	if banner.Feature.ID != featureId {
+		return fmt.Errorf("banner_id=%v of variant doesn't belong to feature_id=%v", banner.ID, featureId)
+	}
+
+	if len(banner.Tags) == 0 {
+		return nil
+	}
+
+	for _, tag := range banner.Tags {
+		if tag.ID == tagId {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("banner_id=%v of variant has other tags than tag_id=%v", banner.ID, tagId)
+}
+
+// PickVariant chooses live variant for the user. The choice depends only on user, feature, tag and variants,
+// so the same user always gets the same variant while the experiment is not changed. The bucket of the user
+// is chosen over all variants of the experiment, so when a variant stops being live only its users are moved
+// to other live variants and users of other variants keep theirs. Variants must be sorted by banner id.
+func PickVariant(variants []Variant, userId int, featureId int, tagId int) Variant {
+	picked, ok := pickByWeight(variants, variantHash("%v:%v:%v", userId, featureId, tagId))
+	if !ok || picked.Live {
+		return picked
+	}
+
+	live := make([]Variant, 0, len(variants))
+	for _, v := range variants {
+		if v.Live {
+			live = append(live, v)
+		}
+	}
+
+	// users of the variant are spread over live variants by weights, independently of their first choice
+	picked, _ = pickByWeight(live, variantHash("%v:%v:%v:%v", userId, featureId, tagId, picked.BannerId))
+
+	return picked
+}
+
+func variantHash(format string, a ...any) uint32 {
+	h := fnv.New32a()
+	fmt.Fprintf(h, format, a...)
+
+	return h.Sum32()
+}
+
+// pickByWeight returns variant which bucket contains hash, buckets are proportional to weights.
+func pickByWeight(variants []Variant, hash uint32) (Variant, bool) {
+	total := 0
+	for _, v := range variants {
+		total += v.Weight
+	}
+
+	if total <= 0 {
+		return Variant{}, false
+	}
+
+	point := int(hash % uint32(total))
+
+	for _, v := range variants {
+		if point < v.Weight {
+			return v, true
+		}
+		point -= v.Weight
+	}
+
+	return variants[len(variants)-1], true
+}
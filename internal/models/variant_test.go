@@ -0,0 +1,74 @@
+package models
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestPickVariant(t *testing.T) {
+	variants := []Variant{{BannerId: 1, Weight: 1, Live: true}, {BannerId: 2, Weight: 3, Live: true}}
+
+	t.Run("Sticky", func(t *testing.T) {
+		for userId := 1; userId <= 100; userId++ {
+			v := PickVariant(variants, userId, 10, 20)
+			require.Equal(t, v, PickVariant(variants, userId, 10, 20))
+		}
+	})
+
+	t.Run("Weights", func(t *testing.T) {
+		counts := make(map[int]int)
+		for userId := 1; userId <= 10000; userId++ {
+			counts[PickVariant(variants, userId, 10, 20).BannerId]++
+		}
+
+		require.InDelta(t, 2500, counts[1], 300)
+		require.InDelta(t, 7500, counts[2], 300)
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		require.Equal(t, Variant{}, PickVariant(nil, 1, 10, 20))
+		require.Equal(t, Variant{}, PickVariant([]Variant{{BannerId: 1, Weight: 1}}, 1, 10, 20))
+	})
+
+	t.Run("Not_Live", func(t *testing.T) {
+		all := []Variant{{BannerId: 1, Weight: 1, Live: true}, {BannerId: 2, Weight: 1, Live: true},
+			{BannerId: 3, Weight: 1, Live: true}}
+		paused := []Variant{all[0], {BannerId: 2, Weight: 1}, all[2]}
+
+		moved := make(map[int]int)
+		for userId := 1; userId <= 3000; userId++ {
+			before := PickVariant(all, userId, 10, 20)
+			after := PickVariant(paused, userId, 10, 20)
+
+			require.NotEqual(t, 2, after.BannerId)
+			if before.BannerId != 2 {
+				require.Equal(t, before, after, "users of live variants must keep them")
+				continue
+			}
+			moved[after.BannerId]++
+		}
+
+		// users of the paused variant are spread over the others
+		require.InDelta(t, moved[1], moved[3], 200)
+	})
+}
+
+func TestValidateVariantBanner(t *testing.T) {
+	banner := AdminBanner{ID: 1, Feature: Feature{ID: 10}}
+	require.NoError(t, ValidateVariantBanner(banner, 10, 20))
+	require.Error(t, ValidateVariantBanner(banner, 11, 20))
+
+	banner.Tags = []Tag{{ID: 20}, {ID: 21}}
+	require.NoError(t, ValidateVariantBanner(banner, 10, 20))
+
+	banner.Tags = []Tag{{ID: 21}}
+	require.Error(t, ValidateVariantBanner(banner, 10, 20))
+}
+
+func TestValidateVariants(t *testing.T) {
+	require.NoError(t, ValidateVariants([]Variant{{BannerId: 1, Weight: 1}, {BannerId: 2, Weight: 5}}))
+	require.Error(t, ValidateVariants(nil))
+	require.Error(t, ValidateVariants([]Variant{{BannerId: 1, Weight: 0}}))
+	require.Error(t, ValidateVariants([]Variant{{BannerId: 0, Weight: 1}}))
+	require.Error(t, ValidateVariants([]Variant{{BannerId: 1, Weight: 1}, {BannerId: 1, Weight: 2}}))
+}
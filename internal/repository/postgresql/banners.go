@@ -4,13 +4,22 @@ import (
 	"avito-test2024-spring/internal/models"
 	"context"
 	"database/sql"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"strings"
+	"time"
 )
 
+// bannerWindowConditions are conditions on banner activation window for each window status, they use @now argument
+var bannerWindowConditions = map[string]string{
+	models.BannerWindowScheduled: `banners.active_from > @now`,
+	models.BannerWindowLive: `(banners.active_from IS NULL OR banners.active_from <= @now)` +
+		` AND (banners.active_until IS NULL OR banners.active_until > @now)`,
+	models.BannerWindowExpired: `banners.active_until <= @now`,
+}
+
 type BannersRepo struct {
 	db *pgxpool.Pool
 }
@@ -24,16 +33,15 @@ func NewBannersRepo(db *pgxpool.Pool) *BannersRepo {
 func (r *BannersRepo) Create(ctx context.Context, banner models.AdminBanner) (int, error) {
 	var id int
 
-	query := `INSERT INTO banners (fk_feature_id, content, is_active, created_at, updated_at) VALUES (
-    @featureId, @contentIn, @isActive, @createdAt, @updatedAt) RETURNING id`
+	query := `INSERT INTO banners (fk_feature_id, content, is_active, active_from, active_until, created_at, updated_at)
+	VALUES (@featureId, @contentIn, @isActive, @activeFrom, @activeUntil, @createdAt, @updatedAt) RETURNING id`
 	args := pgx.NamedArgs{
-		"contentIn": fmt.Sprintf(`{"title": "%v", "text": "%v", "url": "%v"}`, banner.Content.Title, banner.Content.Text, banner.Content.URL),
-		"titleIn":   banner.Content.Title,
-		"textIn":    banner.Content.Text,
-		"urlIn":     banner.Content.URL,
-		"isActive":  banner.IsActive,
-		"createdAt": banner.CreatedAt,
-		"updatedAt": banner.UpdatedAt,
+		"contentIn":   []byte(banner.Content),
+		"isActive":    banner.IsActive,
+		"activeFrom":  banner.ActiveFrom,
+		"activeUntil": banner.ActiveUntil,
+		"createdAt":   banner.CreatedAt,
+		"updatedAt":   banner.UpdatedAt,
 	}
 
 	if banner.Feature.ID == 0 {
@@ -50,22 +58,28 @@ func (r *BannersRepo) Create(ctx context.Context, banner models.AdminBanner) (in
 	err = tx.QueryRow(ctx, query, args).Scan(&id)
 	if err != nil {
 		tx.Rollback(ctx)
-		return -1, err
+		return -1, translateError(err)
 	}
 
 	if banner.Feature.ID != 0 {
 		err = r.insertIntoBannersTags(ctx, tx, id, banner.Tags, banner.Feature.ID)
 		if err != nil {
 			tx.Rollback(ctx)
-			return -1, err
+			return -1, translateError(err)
 		}
 	}
 
+	err = r.insertBannerVersion(ctx, tx, id)
+	if err != nil {
+		tx.Rollback(ctx)
+		return -1, translateError(err)
+	}
+
 	tx.Commit(ctx)
 	return id, nil
 }
 
-func (r *BannersRepo) Update(ctx context.Context, banner models.AdminBanner, toDel []int) error {
+func (r *BannersRepo) Update(ctx context.Context, banner models.AdminBanner, toDel []int, versionsLimit int) error {
 	var oldFeature int
 
 	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
@@ -76,19 +90,25 @@ func (r *BannersRepo) Update(ctx context.Context, banner models.AdminBanner, toD
 	err = tx.QueryRow(ctx, `SELECT COALESCE(fk_feature_id, 0) from banners where id=$1`, banner.ID).Scan(&oldFeature)
 	if err != nil {
 		tx.Rollback(ctx)
-		return err
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.NewNotFoundError(fmt.Sprintf("banner with id=%v not found", banner.ID))
+		}
+		return translateError(err)
 	}
 
 	tx.Commit(ctx)
 
-	query := `UPDATE banners SET fk_feature_id = @featureId, content = @contentIn,
-    is_active = @isActive, created_at = @createdAt, updated_at = @updatedAt WHERE id = @bannerId`
+	query := `UPDATE banners SET fk_feature_id = @featureId, content = @contentIn, is_active = @isActive,
+    active_from = @activeFrom, active_until = @activeUntil, created_at = @createdAt, updated_at = @updatedAt
+    WHERE id = @bannerId`
 	args := pgx.NamedArgs{
-		"bannerId":  banner.ID,
-		"contentIn": fmt.Sprintf(`{"title": "%v", "text": "%v", "url": "%v"}`, banner.Content.Title, banner.Content.Text, banner.Content.URL),
-		"isActive":  banner.IsActive,
-		"createdAt": banner.CreatedAt,
-		"updatedAt": banner.UpdatedAt,
+		"bannerId":    banner.ID,
+		"contentIn":   []byte(banner.Content),
+		"isActive":    banner.IsActive,
+		"activeFrom":  banner.ActiveFrom,
+		"activeUntil": banner.ActiveUntil,
+		"createdAt":   banner.CreatedAt,
+		"updatedAt":   banner.UpdatedAt,
 	}
 
 	if banner.Feature.ID == 0 {
@@ -105,12 +125,12 @@ func (r *BannersRepo) Update(ctx context.Context, banner models.AdminBanner, toD
 	res, err := tx.Exec(ctx, query, args)
 	if err != nil {
 		tx.Rollback(ctx)
-		return err
+		return translateError(err)
 	}
 
 	if res.RowsAffected() == 0 {
 		tx.Rollback(ctx)
-		return errors.New(fmt.Sprintf("banner with id=%v not found", banner.ID))
+		return models.NewNotFoundError(fmt.Sprintf("banner with id=%v not found", banner.ID))
 	}
 
 	if banner.Feature.ID == 0 {
@@ -122,7 +142,19 @@ func (r *BannersRepo) Update(ctx context.Context, banner models.AdminBanner, toD
 		err = r.deleteBannerTag(ctx, tx, banner.ID, toDel)
 		if err != nil {
 			tx.Rollback(ctx)
-			return err
+			return translateError(err)
+		}
+
+		err = r.insertBannerVersion(ctx, tx, banner.ID)
+		if err != nil {
+			tx.Rollback(ctx)
+			return translateError(err)
+		}
+
+		err = r.deleteOldVersions(ctx, tx, banner.ID, versionsLimit)
+		if err != nil {
+			tx.Rollback(ctx)
+			return translateError(err)
 		}
 
 		tx.Commit(ctx)
@@ -138,20 +170,32 @@ func (r *BannersRepo) Update(ctx context.Context, banner models.AdminBanner, toD
 		err = r.deleteBannerTag(ctx, tx, banner.ID, toDel)
 		if err != nil {
 			tx.Rollback(ctx)
-			return err
+			return translateError(err)
 		}
 	}
 
 	err = r.insertIntoBannersTags(ctx, tx, banner.ID, banner.Tags, banner.Feature.ID)
 	if err != nil {
 		tx.Rollback(ctx)
-		return err
+		return translateError(err)
 	}
 
 	err = r.deleteBannerTag(ctx, tx, banner.ID, toDel)
 	if err != nil {
 		tx.Rollback(ctx)
-		return err
+		return translateError(err)
+	}
+
+	err = r.insertBannerVersion(ctx, tx, banner.ID)
+	if err != nil {
+		tx.Rollback(ctx)
+		return translateError(err)
+	}
+
+	err = r.deleteOldVersions(ctx, tx, banner.ID, versionsLimit)
+	if err != nil {
+		tx.Rollback(ctx)
+		return translateError(err)
 	}
 
 	tx.Commit(ctx)
@@ -172,23 +216,91 @@ func (r *BannersRepo) Delete(ctx context.Context, bannerId int) error {
 	res, err := tx.Exec(ctx, query, args)
 	if err != nil {
 		tx.Rollback(ctx)
-		return err
+		return translateError(err)
 	}
 
 	if res.RowsAffected() == 0 {
 		tx.Rollback(ctx)
-		return errors.New(fmt.Sprintf("banner with id=%v not found", bannerId))
+		return models.NewNotFoundError(fmt.Sprintf("banner with id=%v not found", bannerId))
 	}
 
 	tx.Commit(ctx)
 	return nil
 }
 
+func (r *BannersRepo) CountByFeatureTag(ctx context.Context, featureId int, tagId int) (int, error) {
+	var count int
+
+	from, args := bannersFeatureTagFilter(featureId, tagId)
+	query := `SELECT COUNT(DISTINCT banners.id)` + from
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return -1, err
+	}
+
+	err = tx.QueryRow(ctx, query, args).Scan(&count)
+	if err != nil {
+		tx.Rollback(ctx)
+		return -1, translateError(err)
+	}
+
+	tx.Commit(ctx)
+	return count, nil
+}
+
+// DeleteByFeatureTag deletes at most limit banners matching feature and/or tag and returns them with their feature and tags,
+// which are read before deletion.
+func (r *BannersRepo) DeleteByFeatureTag(ctx context.Context, featureId int, tagId int,
+	limit int) ([]models.AdminBanner, error) {
+	from, args := bannersFeatureTagFilter(featureId, tagId)
+	query := `DELETE FROM banners WHERE id IN (SELECT DISTINCT banners.id` + from + ` LIMIT @limitIn)
+	RETURNING id, COALESCE(fk_feature_id::bigint, 0), ` + bannerTagsColumn
+	args["limitIn"] = limit
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, query, args)
+	if err != nil {
+		tx.Rollback(ctx)
+		return nil, translateError(err)
+	}
+
+	banners := make([]models.AdminBanner, 0, limit)
+	for rows.Next() {
+		var banner models.AdminBanner
+		var tagsIds []int
+		err := rows.Scan(&banner.ID, &banner.Feature.ID, &tagsIds)
+		if err != nil {
+			rows.Close()
+			tx.Rollback(ctx)
+			return nil, translateError(err)
+		}
+
+		banner.Tags = tagsFromIds(tagsIds)
+
+		banners = append(banners, banner)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		tx.Rollback(ctx)
+		return nil, translateError(err)
+	}
+
+	tx.Commit(ctx)
+	return banners, nil
+}
+
 func (r *BannersRepo) GetBannerByID(ctx context.Context, bannerId int) (models.AdminBanner, error) {
 	var banner models.AdminBanner
 	var contentJSON []byte
 
-	query := `SELECT id, COALESCE(fk_feature_id::bigint, 0), content, is_active, created_at, updated_at FROM banners WHERE id=@bannerId`
+	query := `SELECT id, COALESCE(fk_feature_id::bigint, 0), content, is_active, active_from, active_until, created_at,
+    updated_at FROM banners WHERE id=@bannerId`
 	args := pgx.NamedArgs{
 		"bannerId": bannerId,
 	}
@@ -198,153 +310,573 @@ func (r *BannersRepo) GetBannerByID(ctx context.Context, bannerId int) (models.A
 		return models.AdminBanner{}, err
 	}
 
-	err = tx.QueryRow(ctx, query, args).Scan(&banner.ID, &banner.Feature.ID, &contentJSON, &banner.IsActive, &banner.CreatedAt, &banner.UpdatedAt)
+	err = tx.QueryRow(ctx, query, args).Scan(&banner.ID, &banner.Feature.ID, &contentJSON, &banner.IsActive,
+		&banner.ActiveFrom, &banner.ActiveUntil, &banner.CreatedAt, &banner.UpdatedAt)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			tx.Rollback(ctx)
-			return models.AdminBanner{}, errors.New(fmt.Sprintf("banner with id=%v not found", bannerId))
+			return models.AdminBanner{}, models.NewNotFoundError(fmt.Sprintf("banner with id=%v not found", bannerId))
 		}
 		tx.Rollback(ctx)
-		return models.AdminBanner{}, err
+		return models.AdminBanner{}, translateError(err)
 	}
 
 	banner.Tags, err = r.getBannerTags(ctx, tx, bannerId)
 	if err != nil {
-		return models.AdminBanner{}, err
-	}
-
-	if err := json.Unmarshal(contentJSON, &banner.Content); err != nil {
 		tx.Rollback(ctx)
-		return models.AdminBanner{}, err
+		return models.AdminBanner{}, translateError(err)
 	}
 
+	banner.Content = contentJSON
+
 	tx.Commit(ctx)
 	return banner, nil
 }
 
-func (r *BannersRepo) GetUserBanner(ctx context.Context, featureId int, tagId int) (models.Banner, int, error) {
+// GetUserBanner returns active banner for tag and feature whose activation window contains current time.
+// Only ID, Content and ActiveUntil of the banner are set.
+func (r *BannersRepo) GetUserBanner(ctx context.Context, featureId int, tagId int) (models.AdminBanner, error) {
+	var banner models.AdminBanner
 	var contentJSON []byte
-	var banner models.Banner
-	var bannerId int
 
-	query := `SELECT content, banners_tags.fk_banner_id FROM banners
+	query := `SELECT content, banners_tags.fk_banner_id, banners.active_until FROM banners
 	JOIN banners_tags ON banners.id = banners_tags.fk_banner_id
 	WHERE banners.fk_feature_id = @featureId AND banners_tags.fk_tag_id = @tagId
-	AND banners.is_active = true`
+	AND banners.is_active = true` + ` AND ` + bannerWindowConditions[models.BannerWindowLive]
 	args := pgx.NamedArgs{
 		"featureId": featureId,
 		"tagId":     tagId,
+		"now":       time.Now(),
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return models.AdminBanner{}, err
+	}
+
+	err = tx.QueryRow(ctx, query, args).Scan(&contentJSON, &banner.ID, &banner.ActiveUntil)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			tx.Rollback(ctx)
+			return models.AdminBanner{}, models.NewNotFoundError(fmt.Sprintf("banner with tag_id=%v and feature_id=%v not found", tagId, featureId))
+		}
+
+		tx.Rollback(ctx)
+		return models.AdminBanner{}, translateError(err)
+	}
+
+	banner.Content = contentJSON
+
+	tx.Commit(ctx)
+	return banner, nil
+}
+
+// GetLiveBanner returns content of banner by id if it is active and inside its activation window.
+func (r *BannersRepo) GetLiveBanner(ctx context.Context, bannerId int) (models.AdminBanner, error) {
+	var banner models.AdminBanner
+	var contentJSON []byte
+
+	query := `SELECT content, id, active_until FROM banners WHERE id = @bannerId AND is_active = true` +
+		` AND ` + bannerWindowConditions[models.BannerWindowLive]
+	args := pgx.NamedArgs{
+		"bannerId": bannerId,
+		"now":      time.Now(),
 	}
 
 	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
-		return models.Banner{}, -1, err
+		return models.AdminBanner{}, err
 	}
 
-	err = tx.QueryRow(ctx, query, args).Scan(&contentJSON, &bannerId)
+	err = tx.QueryRow(ctx, query, args).Scan(&contentJSON, &banner.ID, &banner.ActiveUntil)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			tx.Rollback(ctx)
-			return models.Banner{}, -1, errors.New(fmt.Sprintf("banner with tag_id=%v and feature_id=%v not found", tagId, featureId))
+			return models.AdminBanner{}, models.NewNotFoundError(fmt.Sprintf("banner with id=%v not found", bannerId))
 		}
 
 		tx.Rollback(ctx)
-		return models.Banner{}, -1, err
+		return models.AdminBanner{}, translateError(err)
+	}
+
+	banner.Content = contentJSON
+
+	tx.Commit(ctx)
+	return banner, nil
+}
+
+// GetUserBanners returns in one query live banners of the tag for features in featuresIds and live banners
+// with ids in bannersIds, which are variants of A/B experiments. Banners of the tag have it in Tags, variants
+// which don't belong to the tag have no tags. Only ID, Feature, Tags, Content and ActiveUntil are set.
+func (r *BannersRepo) GetUserBanners(ctx context.Context, tagId int, featuresIds []int,
+	bannersIds []int) ([]models.AdminBanner, error) {
+	query := `SELECT banners.id, COALESCE(banners.fk_feature_id::bigint, 0), content, active_until,
+	bt.fk_banner_id IS NOT NULL FROM banners
+	LEFT JOIN banners_tags bt ON bt.fk_banner_id = banners.id AND bt.fk_tag_id = @tagId
+	WHERE banners.is_active = true AND ` + bannerWindowConditions[models.BannerWindowLive] + `
+	AND ((bt.fk_banner_id IS NOT NULL AND banners.fk_feature_id = ANY(@featuresIds)) OR banners.id = ANY(@bannersIds))`
+	args := pgx.NamedArgs{
+		"tagId":       tagId,
+		"featuresIds": featuresIds,
+		"bannersIds":  bannersIds,
+		"now":         time.Now(),
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, query, args)
+	if err != nil {
+		tx.Rollback(ctx)
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	banners := make([]models.AdminBanner, 0)
+	for rows.Next() {
+		var banner models.AdminBanner
+		var contentJSON []byte
+		var tagged bool
+
+		err := rows.Scan(&banner.ID, &banner.Feature.ID, &contentJSON, &banner.ActiveUntil, &tagged)
+		if err != nil {
+			tx.Rollback(ctx)
+			return nil, translateError(err)
+		}
+
+		banner.Content = contentJSON
+		if tagged {
+			banner.Tags = []models.Tag{{ID: tagId}}
+		}
+
+		banners = append(banners, banner)
 	}
 
-	if err := json.Unmarshal(contentJSON, &banner); err != nil {
+	if err := rows.Err(); err != nil {
 		tx.Rollback(ctx)
-		return models.Banner{}, -1, err
+		return nil, translateError(err)
 	}
 
 	tx.Commit(ctx)
-	return banner, bannerId, nil
+	return banners, nil
 }
 
-func (r *BannersRepo) GetAllBanners(ctx context.Context, featureId int,
-	tagId int, limit int, offset int) ([]models.AdminBanner, error) {
-	query := `SELECT banners.id, COALESCE(banners.fk_feature_id::bigint, 0), content, is_active, created_at, updated_at` +
-		` FROM banners`
-	args := pgx.NamedArgs{}
+// bannerSortColumns are columns of banners table for each sort of banners list
+var bannerSortColumns = map[string]string{
+	models.BannerSortId:        `banners.id`,
+	models.BannerSortCreatedAt: `banners.created_at`,
+	models.BannerSortUpdatedAt: `banners.updated_at`,
+}
 
-	if featureId != 0 && tagId != 0 {
-		query += ` JOIN banners_tags ON banners.id = banners_tags.fk_banner_id` +
-			` JOIN tags ON banners_tags.fk_tag_id = tags.id` + ` WHERE tags.id = @tagId AND banners.fk_feature_id = @featureId`
+// GetAllBanners returns up to limit banners matching filter which follow cursor in the sort order
+// and the total number of banners matching filter.
+func (r *BannersRepo) GetAllBanners(ctx context.Context, filter models.BannerFilter, cursor models.Cursor,
+	limit int) ([]models.AdminBanner, int, error) {
+	var total int
 
-		args["tagId"] = tagId
-		args["featureId"] = featureId
+	conditions := make([]string, 0)
+	args := pgx.NamedArgs{
+		"limitIn": limit,
 	}
 
-	if featureId != 0 && tagId == 0 {
-		query += ` WHERE banners.fk_feature_id = @featureId`
+	if len(filter.TagsIds) > 0 {
+		conditions = append(conditions, `EXISTS (SELECT 1 FROM banners_tags bt WHERE bt.fk_banner_id = banners.id`+
+			` AND bt.fk_tag_id = ANY(@tagsIds))`)
+		args["tagsIds"] = filter.TagsIds
+	}
 
-		args["featureId"] = featureId
+	if len(filter.FeaturesIds) > 0 {
+		conditions = append(conditions, `banners.fk_feature_id = ANY(@featuresIds)`)
+		args["featuresIds"] = filter.FeaturesIds
 	}
 
-	if featureId == 0 && tagId != 0 {
-		query += ` JOIN banners_tags ON banners.id = banners_tags.fk_banner_id` +
-			` JOIN tags ON banners_tags.fk_tag_id = tags.id` + ` WHERE tags.id = @tagId`
+	if filter.IsActive != nil {
+		conditions = append(conditions, `banners.is_active = @isActive`)
+		args["isActive"] = *filter.IsActive
+	}
 
-		args["tagId"] = tagId
+	if filter.Status != "" {
+		conditions = append(conditions, bannerWindowConditions[filter.Status])
+		args["now"] = time.Now()
 	}
 
-	query += ` ORDER BY banners.id`
+	if filter.CreatedFrom != nil {
+		conditions = append(conditions, `banners.created_at >= @createdFrom`)
+		args["createdFrom"] = *filter.CreatedFrom
+	}
 
-	if offset != 0 {
-		query += ` OFFSET @offsetIn`
-		args["offsetIn"] = offset
+	if filter.CreatedTo != nil {
+		conditions = append(conditions, `banners.created_at <= @createdTo`)
+		args["createdTo"] = *filter.CreatedTo
 	}
 
-	if limit != 0 {
-		query += ` LIMIT @limitIn`
-		args["limitIn"] = limit
+	if filter.UpdatedFrom != nil {
+		conditions = append(conditions, `banners.updated_at >= @updatedFrom`)
+		args["updatedFrom"] = *filter.UpdatedFrom
 	}
 
-	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if filter.UpdatedTo != nil {
+		conditions = append(conditions, `banners.updated_at <= @updatedTo`)
+		args["updatedTo"] = *filter.UpdatedTo
+	}
+
+	if filter.Search != "" {
+		conditions = append(conditions, `banners.search_vector @@ websearch_to_tsquery('simple', @search)`)
+		args["search"] = filter.Search
+	}
+
+	where := ``
+	if len(conditions) > 0 {
+		where = ` WHERE ` + strings.Join(conditions, ` AND `)
+	}
+
+	countQuery := `SELECT count(*) FROM banners` + where
+
+	sortColumn := bannerSortColumns[filter.SortBy]
+	direction, compare := ` ASC`, `>`
+	if filter.SortDesc {
+		direction, compare = ` DESC`, `<`
+	}
+
+	if !cursor.IsZero() {
+		if sortColumn == bannerSortColumns[models.BannerSortId] {
+			conditions = append(conditions, `banners.id `+compare+` @afterId`)
+		} else {
+			conditions = append(conditions, `(`+sortColumn+`, banners.id) `+compare+` (@afterTime, @afterId)`)
+			args["afterTime"] = cursor.AfterTime
+		}
+		args["afterId"] = cursor.AfterId
+	}
+
+	// tags are aggregated by subquery instead of a query per banner, so the page is loaded in one round trip
+	query := `SELECT banners.id, COALESCE(banners.fk_feature_id::bigint, 0), content, is_active, active_from,` +
+		` active_until, created_at, updated_at, ` + bannerTagsColumn + ` FROM banners`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, ` AND `)
+	}
+
+	query += ` ORDER BY ` + sortColumn + direction
+	if sortColumn != bannerSortColumns[models.BannerSortId] {
+		query += `, banners.id` + direction
+	}
+	query += ` LIMIT @limitIn`
+
+	// page and total count are read from the same snapshot
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
 	if err != nil {
-		return nil, err
+		return nil, 0, err
+	}
+
+	err = tx.QueryRow(ctx, countQuery, args).Scan(&total)
+	if err != nil {
+		tx.Rollback(ctx)
+		return nil, 0, translateError(err)
 	}
 
 	rows, err := tx.Query(ctx, query, args)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			tx.Commit(ctx)
-			return nil, nil
+		tx.Rollback(ctx)
+		return nil, 0, translateError(err)
+	}
+	defer rows.Close()
+
+	banners := make([]models.AdminBanner, 0)
+	for rows.Next() {
+		banner := models.AdminBanner{}
+		var contentJSON []byte
+		var tagsIds []int
+		err := rows.Scan(&banner.ID, &banner.Feature.ID, &contentJSON, &banner.IsActive, &banner.ActiveFrom,
+			&banner.ActiveUntil, &banner.CreatedAt, &banner.UpdatedAt, &tagsIds)
+		if err != nil {
+			tx.Rollback(ctx)
+			return nil, 0, translateError(err)
 		}
 
+		banner.Content = contentJSON
+		banner.Tags = tagsFromIds(tagsIds)
+
+		banners = append(banners, banner)
+	}
+
+	if err := rows.Err(); err != nil {
 		tx.Rollback(ctx)
+		return nil, 0, translateError(err)
+	}
+
+	tx.Commit(ctx)
+	return banners, total, nil
+}
+
+func (r *BannersRepo) GetBannerVersions(ctx context.Context, bannerId int) ([]models.BannerVersion, error) {
+	var exists bool
+
+	query := `SELECT version, content, COALESCE(feature_id::bigint, 0), tags_ids, is_active, active_from,
+	active_until, updated_at FROM banners_versions WHERE fk_banner_id = @bannerId ORDER BY version DESC`
+	args := pgx.NamedArgs{
+		"bannerId": bannerId,
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
 		return nil, err
 	}
+
+	err = tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM banners WHERE id = @bannerId)`, args).Scan(&exists)
+	if err != nil {
+		tx.Rollback(ctx)
+		return nil, translateError(err)
+	}
+
+	if !exists {
+		tx.Rollback(ctx)
+		return nil, models.NewNotFoundError(fmt.Sprintf("banner with id=%v not found", bannerId))
+	}
+
+	rows, err := tx.Query(ctx, query, args)
+	if err != nil {
+		tx.Rollback(ctx)
+		return nil, translateError(err)
+	}
 	defer rows.Close()
 
-	banners := make([]models.AdminBanner, 0)
+	versions := make([]models.BannerVersion, 0)
 	for rows.Next() {
-		banner := models.AdminBanner{}
+		version := models.BannerVersion{}
 		var contentJSON []byte
-		err := rows.Scan(&banner.ID, &banner.Feature.ID, &contentJSON, &banner.IsActive, &banner.CreatedAt, &banner.UpdatedAt)
+		var tagsIds []int
+
+		err := rows.Scan(&version.Version, &contentJSON, &version.Feature.ID, &tagsIds, &version.IsActive,
+			&version.ActiveFrom, &version.ActiveUntil, &version.UpdatedAt)
 		if err != nil {
 			tx.Rollback(ctx)
-			return nil, err
+			return nil, translateError(err)
 		}
 
-		if err := json.Unmarshal(contentJSON, &banner.Content); err != nil {
+		version.Content = contentJSON
+		version.Tags = tagsFromIds(tagsIds)
+
+		versions = append(versions, version)
+	}
+
+	if err := rows.Err(); err != nil {
+		tx.Rollback(ctx)
+		return nil, translateError(err)
+	}
+
+	tx.Commit(ctx)
+	return versions, nil
+}
+
+func (r *BannersRepo) ActivateVersion(ctx context.Context, bannerId int, version int, versionsLimit int) error {
+	var contentJSON []byte
+	var featureId int
+	var tagsIds []int
+	var isActive bool
+	var activeFrom, activeUntil *time.Time
+
+	query := `SELECT content, COALESCE(feature_id::bigint, 0), tags_ids, is_active, active_from, active_until
+	FROM banners_versions WHERE fk_banner_id = @bannerId AND version = @version`
+	args := pgx.NamedArgs{
+		"bannerId": bannerId,
+		"version":  version,
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	err = tx.QueryRow(ctx, query, args).Scan(&contentJSON, &featureId, &tagsIds, &isActive, &activeFrom, &activeUntil)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
 			tx.Rollback(ctx)
-			return nil, err
+			return models.NewNotFoundError(fmt.Sprintf("banner with id=%v and version=%v not found", bannerId, version))
 		}
 
-		tags, err := r.getBannerTags(ctx, tx, banner.ID)
+		tx.Rollback(ctx)
+		return translateError(err)
+	}
+
+	updateQuery := `UPDATE banners SET fk_feature_id = @featureId, content = @contentIn,
+    is_active = @isActive, active_from = @activeFrom, active_until = @activeUntil, updated_at = @updatedAt
+    WHERE id = @bannerId`
+	updateArgs := pgx.NamedArgs{
+		"bannerId":    bannerId,
+		"contentIn":   contentJSON,
+		"isActive":    isActive,
+		"activeFrom":  activeFrom,
+		"activeUntil": activeUntil,
+		"updatedAt":   time.Now(),
+	}
+
+	if featureId == 0 {
+		updateArgs["featureId"] = sql.NullInt64{}
+	} else {
+		updateArgs["featureId"] = featureId
+	}
+
+	_, err = tx.Exec(ctx, updateQuery, updateArgs)
+	if err != nil {
+		tx.Rollback(ctx)
+		return translateError(err)
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM banners_tags WHERE fk_banner_id = @bannerId`, args)
+	if err != nil {
+		tx.Rollback(ctx)
+		return translateError(err)
+	}
+
+	if featureId != 0 {
+		err = r.insertIntoBannersTags(ctx, tx, bannerId, tagsFromIds(tagsIds), featureId)
 		if err != nil {
 			tx.Rollback(ctx)
-			return nil, err
+			return translateError(err)
 		}
+	}
 
-		banner.Tags = tags
+	err = r.insertBannerVersion(ctx, tx, bannerId)
+	if err != nil {
+		tx.Rollback(ctx)
+		return translateError(err)
+	}
 
-		banners = append(banners, banner)
+	err = r.deleteOldVersions(ctx, tx, bannerId, versionsLimit)
+	if err != nil {
+		tx.Rollback(ctx)
+		return translateError(err)
 	}
 
 	tx.Commit(ctx)
-	return banners, nil
+	return nil
+}
+
+// deleteOldVersions keeps only limit latest versions of the banner. It runs in the transaction which adds
+// a version, so the history is pruned only if the change is saved and the change is not reported failed
+// after it is committed.
+func (r *BannersRepo) deleteOldVersions(ctx context.Context, tx pgx.Tx, bannerId int, limit int) error {
+	query := `DELETE FROM banners_versions WHERE fk_banner_id = @bannerId AND version <=
+	(SELECT MAX(version) FROM banners_versions WHERE fk_banner_id = @bannerId) - @limitIn`
+	args := pgx.NamedArgs{
+		"bannerId": bannerId,
+		"limitIn":  limit,
+	}
+
+	_, err := tx.Exec(ctx, query, args)
+
+	return err
+}
+
+func (r *BannersRepo) insertBannerVersion(ctx context.Context, tx pgx.Tx, bannerId int) error {
+	query := `INSERT INTO banners_versions (fk_banner_id, version, content, feature_id, tags_ids, is_active,
+		active_from, active_until, updated_at)
+	SELECT banners.id,
+		COALESCE((SELECT MAX(version) FROM banners_versions WHERE fk_banner_id = banners.id), 0) + 1,
+		banners.content, banners.fk_feature_id,
+		ARRAY(SELECT fk_tag_id FROM banners_tags WHERE fk_banner_id = banners.id ORDER BY fk_tag_id),
+		banners.is_active, banners.active_from, banners.active_until, banners.updated_at
+	FROM banners WHERE banners.id = @bannerId`
+	args := pgx.NamedArgs{
+		"bannerId": bannerId,
+	}
+
+	_, err := tx.Exec(ctx, query, args)
+
+	return err
+}
+
+func bannersFeatureTagFilter(featureId int, tagId int) (string, pgx.NamedArgs) {
+	args := pgx.NamedArgs{}
+
+	if tagId == 0 {
+		args["featureId"] = featureId
+		return ` FROM banners WHERE banners.fk_feature_id = @featureId`, args
+	}
+
+	from := ` FROM banners JOIN banners_tags ON banners.id = banners_tags.fk_banner_id WHERE banners_tags.fk_tag_id = @tagId`
+	args["tagId"] = tagId
+
+	if featureId != 0 {
+		from += ` AND banners.fk_feature_id = @featureId`
+		args["featureId"] = featureId
+	}
+
+	return from, args
+}
+
+// GetWindowBoundaryBanners returns ids of banners whose activation window starts or ends in (from, to].
+func (r *BannersRepo) GetWindowBoundaryBanners(ctx context.Context, from time.Time, to time.Time) ([]int, error) {
+	query := `SELECT id FROM banners WHERE (active_from > @fromIn AND active_from <= @toIn)
+	OR (active_until > @fromIn AND active_until <= @toIn)`
+	args := pgx.NamedArgs{
+		"fromIn": from,
+		"toIn":   to,
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, query, args)
+	if err != nil {
+		tx.Rollback(ctx)
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	ids := make([]int, 0)
+	for rows.Next() {
+		var id int
+		err := rows.Scan(&id)
+		if err != nil {
+			tx.Rollback(ctx)
+			return nil, translateError(err)
+		}
+
+		ids = append(ids, id)
+	}
+
+	tx.Commit(ctx)
+	return ids, nil
+}
+
+// GetConflicts returns pairs of the feature and the given tags which are used by banners other than bannerId.
+func (r *BannersRepo) GetConflicts(ctx context.Context, bannerId int, featureId int,
+	tagsIds []int) ([]models.BannerConflict, error) {
+	query := `SELECT fk_tag_id, fk_feature_id, fk_banner_id FROM banners_tags
+	WHERE fk_feature_id = @featureId AND fk_tag_id = ANY(@tagsIds) AND fk_banner_id <> @bannerId ORDER BY fk_tag_id`
+	args := pgx.NamedArgs{
+		"bannerId":  bannerId,
+		"featureId": featureId,
+		"tagsIds":   tagsIds,
+	}
+
+	// single read doesn't need a transaction
+	rows, err := r.db.Query(ctx, query, args)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	conflicts := make([]models.BannerConflict, 0)
+	for rows.Next() {
+		var conflict models.BannerConflict
+		err := rows.Scan(&conflict.TagId, &conflict.FeatureId, &conflict.BannerId)
+		if err != nil {
+			return nil, translateError(err)
+		}
+
+		conflicts = append(conflicts, conflict)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, translateError(err)
+	}
+
+	return conflicts, nil
 }
 
 func (r *BannersRepo) insertIntoBannersTags(ctx context.Context, tx pgx.Tx, bannerId int, tagsId []models.Tag, featureId int) error {
@@ -373,34 +905,34 @@ func (r *BannersRepo) insertIntoBannersTags(ctx context.Context, tx pgx.Tx, bann
 	return nil
 }
 
+// bannerTagsColumn selects ids of banner tags as an array, it is used in queries of banners lists.
+const bannerTagsColumn = `ARRAY(SELECT bt.fk_tag_id FROM banners_tags bt WHERE bt.fk_banner_id = banners.id` +
+	` ORDER BY bt.fk_tag_id) AS tags_ids`
+
+// getBannerTags reads tags of banner inside tx, so they belong to the same snapshot as the banner.
 func (r *BannersRepo) getBannerTags(ctx context.Context, tx pgx.Tx, bannerId int) ([]models.Tag, error) {
-	query := `SELECT fk_tag_id FROM banners_tags WHERE fk_banner_id = @bannerId`
+	var tagsIds []int
+
+	query := `SELECT ARRAY(SELECT fk_tag_id FROM banners_tags WHERE fk_banner_id = @bannerId ORDER BY fk_tag_id)`
 	args := pgx.NamedArgs{
 		"bannerId": bannerId,
 	}
 
-	rows, err := r.db.Query(ctx, query, args)
+	err := tx.QueryRow(ctx, query, args).Scan(&tagsIds)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, nil
-		}
-
 		return nil, err
 	}
-	defer rows.Close()
 
-	tags := make([]models.Tag, 0)
-	for rows.Next() {
-		var tag models.Tag
-		err := rows.Scan(&tag.ID)
-		if err != nil {
-			return nil, err
-		}
+	return tagsFromIds(tagsIds), nil
+}
 
-		tags = append(tags, tag)
+func tagsFromIds(tagsIds []int) []models.Tag {
+	tags := make([]models.Tag, 0, len(tagsIds))
+	for _, id := range tagsIds {
+		tags = append(tags, models.Tag{ID: id})
 	}
 
-	return tags, err
+	return tags
 }
 
 func (r *BannersRepo) deleteBannerTag(ctx context.Context, tx pgx.Tx, bannerId int, toDel []int) error {
@@ -419,12 +951,12 @@ func (r *BannersRepo) deleteBannerTag(ctx context.Context, tx pgx.Tx, bannerId i
 		res, err := tx.Exec(ctx, query, args)
 		if err != nil {
 			tx.Rollback(ctx)
-			return err
+			return translateError(err)
 		}
 
 		if res.RowsAffected() == 0 {
 			tx.Rollback(ctx)
-			return errors.New(fmt.Sprintf("banner_tag with banner_id=%v and tag_id=%v not found", bannerId, t))
+			return models.NewNotFoundError(fmt.Sprintf("banner_tag with banner_id=%v and tag_id=%v not found", bannerId, t))
 		}
 	}
 
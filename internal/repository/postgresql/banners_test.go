@@ -0,0 +1,130 @@
+package postgresql
+
+import (
+	"avito-test2024-spring/pkg/database/postgresql/migrations"
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+	"time"
+)
+
+// newTestDB connects to DB from dsn with search_path set to a temporary schema migrated to the latest version.
+// The schema is dropped after test.
+func newTestDB(tb testing.TB, dsn string) *pgxpool.Pool {
+	ctx := context.Background()
+	schema := fmt.Sprintf("test_%v_%v", os.Getpid(), time.Now().UnixNano())
+
+	cfg, err := pgxpool.ParseConfig(dsn)
+	require.NoError(tb, err)
+	cfg.ConnConfig.RuntimeParams["search_path"] = schema
+
+	db, err := pgxpool.NewWithConfig(ctx, cfg)
+	require.NoError(tb, err)
+
+	tb.Cleanup(func() {
+		db.Exec(context.Background(), fmt.Sprintf(`DROP SCHEMA IF EXISTS %v CASCADE`, schema))
+		db.Close()
+	})
+
+	_, err = db.Exec(ctx, fmt.Sprintf(`CREATE SCHEMA %v`, schema))
+	require.NoError(tb, err)
+
+	migrator, err := migrations.NewMigrator(db)
+	require.NoError(tb, err)
+
+	_, err = migrator.Up(ctx)
+	require.NoError(tb, err)
+
+	return db
+}
+
+// newTestRepo creates banners repo on DB from TEST_POSTGRES_DSN, tests are skipped if it is not set.
+func newTestRepo(t *testing.T) *BannersRepo {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+
+	return NewBannersRepo(newTestDB(t, dsn))
+}
+
+func TestBannersRepo_GetWindowBoundaryBanners(t *testing.T) {
+	r := newTestRepo(t)
+	ctx := context.Background()
+
+	from := time.Date(2024, 4, 10, 12, 0, 0, 0, time.UTC)
+	to := from.Add(10 * time.Second)
+	// window is set in other time zone than the range of the scheduler, they must be compared as instants
+	zone := time.FixedZone("UTC+3", 3*60*60)
+	at := func(d time.Duration) *time.Time {
+		t := from.Add(d).In(zone)
+		return &t
+	}
+
+	windows := []struct {
+		id          int
+		activeFrom  *time.Time
+		activeUntil *time.Time
+	}{
+		{id: 1, activeFrom: at(5 * time.Second)},
+		{id: 2, activeFrom: at(-time.Hour), activeUntil: at(10 * time.Second)},
+		{id: 3, activeFrom: at(0)},
+		{id: 4, activeFrom: at(-time.Hour), activeUntil: at(time.Hour)},
+		{id: 5, activeUntil: at(11 * time.Second)},
+		{id: 6},
+	}
+	for _, w := range windows {
+		_, err := r.db.Exec(ctx, `INSERT INTO banners (id, content, is_active, active_from, active_until, created_at,
+		updated_at) VALUES (@id, '{}', true, @activeFrom, @activeUntil, now(), now())`, pgx.NamedArgs{
+			"id":          w.id,
+			"activeFrom":  w.activeFrom,
+			"activeUntil": w.activeUntil,
+		})
+		require.NoError(t, err)
+	}
+
+	ids, err := r.GetWindowBoundaryBanners(ctx, from, to)
+	require.NoError(t, err)
+	// start of the range is excluded, because it was checked by the previous run, and the end is included
+	require.ElementsMatch(t, []int{1, 2}, ids)
+}
+
+func TestBannersRepo_ActivateVersion_ActiveWindow(t *testing.T) {
+	r := newTestRepo(t)
+	ctx := context.Background()
+
+	activeFrom := time.Date(2024, 4, 10, 12, 0, 0, 0, time.UTC)
+	activeUntil := activeFrom.Add(time.Hour)
+
+	_, err := r.db.Exec(ctx, `INSERT INTO banners (id, content, is_active, active_from, active_until, created_at,
+	updated_at) VALUES (1, '{}', true, @activeFrom, @activeUntil, now(), now())`, pgx.NamedArgs{
+		"activeFrom":  activeFrom,
+		"activeUntil": activeUntil,
+	})
+	require.NoError(t, err)
+
+	tx, err := r.db.Begin(ctx)
+	require.NoError(t, err)
+	require.NoError(t, r.insertBannerVersion(ctx, tx, 1))
+	require.NoError(t, tx.Commit(ctx))
+
+	_, err = r.db.Exec(ctx, `UPDATE banners SET active_from = NULL, active_until = now() WHERE id = 1`)
+	require.NoError(t, err)
+
+	versions, err := r.GetBannerVersions(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, versions, 1)
+	require.True(t, activeFrom.Equal(*versions[0].ActiveFrom))
+	require.True(t, activeUntil.Equal(*versions[0].ActiveUntil))
+
+	require.NoError(t, r.ActivateVersion(ctx, 1, 1, 10))
+
+	banner, err := r.GetBannerByID(ctx, 1)
+	require.NoError(t, err)
+	require.True(t, activeFrom.Equal(*banner.ActiveFrom))
+	require.True(t, activeUntil.Equal(*banner.ActiveUntil))
+}
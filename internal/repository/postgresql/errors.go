@@ -0,0 +1,53 @@
+package postgresql
+
+import (
+	"avito-test2024-spring/internal/models"
+	"errors"
+	"github.com/jackc/pgx/v5/pgconn"
+	"strings"
+)
+
+// https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	uniqueViolation           = "23505"
+	foreignKeyViolation       = "23503"
+	notNullViolation          = "23502"
+	checkViolation            = "23514"
+	invalidTextRepresentation = "22P02"
+)
+
+// translateError converts constraint violations of postgres into domain errors, other errors are returned as is.
+func translateError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+
+	message := pgErr.Detail
+	if message == "" {
+		message = pgErr.Message
+	}
+
+	switch pgErr.Code {
+	case uniqueViolation:
+		switch pgErr.ConstraintName {
+		case "unique_banner_tag_feature":
+			return models.NewConflictError("banner with such feature_id and tag_id already exists")
+		case "unique_tag_name":
+			return models.NewConflictError("tag with such name already exists")
+		case "unique_feature_name":
+			return models.NewConflictError("feature with such name already exists")
+		}
+		return models.NewConflictError(message)
+	case foreignKeyViolation:
+		// row which is referenced by other rows can't be deleted, the request itself is correct
+		if strings.HasPrefix(pgErr.Message, "update or delete") {
+			return models.NewConflictError(message)
+		}
+		return models.NewValidationError(message)
+	case notNullViolation, checkViolation, invalidTextRepresentation:
+		return models.NewValidationError(message)
+	}
+
+	return err
+}
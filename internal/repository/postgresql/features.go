@@ -3,12 +3,16 @@ package postgresql
 import (
 	"avito-test2024-spring/internal/models"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// featureColumns are columns of features scanned into models.Feature
+const featureColumns = `id, COALESCE(name, ''), description, owner_team`
+
 type FeaturesRepo struct {
 	db *pgxpool.Pool
 }
@@ -19,20 +23,26 @@ func NewFeaturesRepo(db *pgxpool.Pool) *FeaturesRepo {
 	}
 }
 
-func (r *FeaturesRepo) Create(ctx context.Context) (int, error) {
+func (r *FeaturesRepo) Create(ctx context.Context, metadata models.Metadata) (int, error) {
 	var id int
 
-	query := `INSERT INTO features default values RETURNING id`
+	query := `INSERT INTO features (name, description, owner_team) VALUES (NULLIF(@name, ''), @description, @ownerTeam)
+	RETURNING id`
+	args := pgx.NamedArgs{
+		"name":        metadata.Name,
+		"description": metadata.Description,
+		"ownerTeam":   metadata.OwnerTeam,
+	}
 
 	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
 		return -1, err
 	}
 
-	err = tx.QueryRow(ctx, query).Scan(&id)
+	err = tx.QueryRow(ctx, query, args).Scan(&id)
 	if err != nil {
 		tx.Rollback(ctx)
-		return -1, err
+		return -1, translateError(err)
 	}
 
 	tx.Commit(ctx)
@@ -55,68 +65,201 @@ func (r *FeaturesRepo) Delete(ctx context.Context, featureId int) error {
 	res, err := tx.Exec(ctx, subQuery, args)
 	if err != nil {
 		tx.Rollback(ctx)
-		return err
+		return translateError(err)
 	}
 
 	res, err = tx.Exec(ctx, query, args)
 	if err != nil {
 		tx.Rollback(ctx)
+		return translateError(err)
+	}
+
+	if res.RowsAffected() == 0 {
+		tx.Rollback(ctx)
+		return models.NewNotFoundError(fmt.Sprintf("feature with id=%v not found", featureId))
+	}
+
+	tx.Commit(ctx)
+	return nil
+}
+
+func (r *FeaturesRepo) Update(ctx context.Context, feature models.Feature) error {
+	query := `UPDATE features SET name = NULLIF(@name, ''), description = @description, owner_team = @ownerTeam
+	WHERE id = @featureId`
+	args := pgx.NamedArgs{
+		"featureId":   feature.ID,
+		"name":        feature.Name,
+		"description": feature.Description,
+		"ownerTeam":   feature.OwnerTeam,
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
 		return err
 	}
 
+	res, err := tx.Exec(ctx, query, args)
+	if err != nil {
+		tx.Rollback(ctx)
+		return translateError(err)
+	}
+
 	if res.RowsAffected() == 0 {
 		tx.Rollback(ctx)
-		return errors.New(fmt.Sprintf("feature with id=%v not found", featureId))
+		return models.NewNotFoundError(fmt.Sprintf("feature with id=%v not found", feature.ID))
 	}
 
 	tx.Commit(ctx)
 	return nil
 }
 
-func (r *FeaturesRepo) GetAllFeatures(ctx context.Context, limit int, offset int) ([]models.Feature, error) {
-	query := `SELECT * FROM features order by id offset @offsetIn limit @limitIn`
-	args := pgx.NamedArgs{}
-	if limit == 0 {
-		query = `SELECT * FROM features order by id offset @offsetIn`
-		args = pgx.NamedArgs{
-			"offsetIn": offset,
-		}
-	} else {
-		args = pgx.NamedArgs{
-			"offsetIn": offset,
-			"limitIn":  limit,
-		}
+func (r *FeaturesRepo) GetFeatureById(ctx context.Context, featureId int) (models.Feature, error) {
+	query := `SELECT ` + featureColumns + ` FROM features WHERE id = @featureId`
+	args := pgx.NamedArgs{
+		"featureId": featureId,
+	}
+
+	return r.getFeature(ctx, query, args, fmt.Sprintf("feature with id=%v not found", featureId))
+}
+
+func (r *FeaturesRepo) GetFeatureByName(ctx context.Context, name string) (models.Feature, error) {
+	query := `SELECT ` + featureColumns + ` FROM features WHERE name = @name`
+	args := pgx.NamedArgs{
+		"name": name,
 	}
 
+	return r.getFeature(ctx, query, args, fmt.Sprintf("feature with name=%q not found", name))
+}
+
+func (r *FeaturesRepo) getFeature(ctx context.Context, query string, args pgx.NamedArgs, notFound string) (models.Feature, error) {
+	var feature models.Feature
+
 	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
-		return nil, err
+		return models.Feature{}, err
 	}
 
-	rows, err := tx.Query(ctx, query, args)
+	err = tx.QueryRow(ctx, query, args).Scan(&feature.ID, &feature.Name, &feature.Description, &feature.OwnerTeam)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			tx.Commit(ctx)
-			return nil, err
+			tx.Rollback(ctx)
+			return models.Feature{}, models.NewNotFoundError(notFound)
 		}
 
 		tx.Rollback(ctx)
-		return nil, err
+		return models.Feature{}, translateError(err)
+	}
+
+	tx.Commit(ctx)
+	return feature, nil
+}
+
+// GetAllFeatures returns up to limit features with id greater than afterId and the total number of features.
+func (r *FeaturesRepo) GetAllFeatures(ctx context.Context, afterId int, limit int) ([]models.Feature, int, error) {
+	var total int
+
+	query := `SELECT ` + featureColumns + ` FROM features WHERE id > @afterId ORDER BY id LIMIT @limitIn`
+	args := pgx.NamedArgs{
+		"afterId": afterId,
+		"limitIn": limit,
+	}
+
+	// page and total count are read from the same snapshot
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = tx.QueryRow(ctx, `SELECT count(*) FROM features`).Scan(&total)
+	if err != nil {
+		tx.Rollback(ctx)
+		return nil, 0, translateError(err)
+	}
+
+	rows, err := tx.Query(ctx, query, args)
+	if err != nil {
+		tx.Rollback(ctx)
+		return nil, 0, translateError(err)
 	}
 	defer rows.Close()
 
 	features := make([]models.Feature, 0)
 	for rows.Next() {
 		feature := models.Feature{}
-		err := rows.Scan(&feature.ID)
+		err := rows.Scan(&feature.ID, &feature.Name, &feature.Description, &feature.OwnerTeam)
 		if err != nil {
 			tx.Rollback(ctx)
-			return nil, err
+			return nil, 0, translateError(err)
 		}
 
 		features = append(features, feature)
 	}
 
+	if err := rows.Err(); err != nil {
+		tx.Rollback(ctx)
+		return nil, 0, translateError(err)
+	}
+
+	tx.Commit(ctx)
+	return features, total, nil
+}
+
+func (r *FeaturesRepo) SetContentSchema(ctx context.Context, featureId int, schema json.RawMessage) error {
+	query := `UPDATE features SET content_schema = @schema WHERE id = @featureId`
+	args := pgx.NamedArgs{
+		"featureId": featureId,
+	}
+
+	if len(schema) == 0 {
+		args["schema"] = nil
+	} else {
+		args["schema"] = []byte(schema)
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.Exec(ctx, query, args)
+	if err != nil {
+		tx.Rollback(ctx)
+		return translateError(err)
+	}
+
+	if res.RowsAffected() == 0 {
+		tx.Rollback(ctx)
+		return models.NewNotFoundError(fmt.Sprintf("feature with id=%v not found", featureId))
+	}
+
+	tx.Commit(ctx)
+	return nil
+}
+
+func (r *FeaturesRepo) GetContentSchema(ctx context.Context, featureId int) (json.RawMessage, error) {
+	var schema []byte
+
+	query := `SELECT content_schema FROM features WHERE id = @featureId`
+	args := pgx.NamedArgs{
+		"featureId": featureId,
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.QueryRow(ctx, query, args).Scan(&schema)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			tx.Rollback(ctx)
+			return nil, models.NewNotFoundError(fmt.Sprintf("feature with id=%v not found", featureId))
+		}
+
+		tx.Rollback(ctx)
+		return nil, translateError(err)
+	}
+
 	tx.Commit(ctx)
-	return features, nil
+	return schema, nil
 }
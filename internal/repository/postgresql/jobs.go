@@ -0,0 +1,197 @@
+package postgresql
+
+import (
+	"avito-test2024-spring/internal/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"time"
+)
+
+type JobsRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewJobsRepo(db *pgxpool.Pool) *JobsRepo {
+	return &JobsRepo{
+		db: db,
+	}
+}
+
+func (r *JobsRepo) Create(ctx context.Context, job models.Job) (int, error) {
+	var id int
+
+	query := `INSERT INTO jobs (feature_id, tag_id, status, created_at, updated_at) VALUES (
+    @featureId, @tagId, @status, @createdAt, @updatedAt) RETURNING id`
+	args := pgx.NamedArgs{
+		"status":    job.Status,
+		"createdAt": job.CreatedAt,
+		"updatedAt": job.UpdatedAt,
+	}
+
+	if job.FeatureId == 0 {
+		args["featureId"] = sql.NullInt64{}
+	} else {
+		args["featureId"] = job.FeatureId
+	}
+
+	if job.TagId == 0 {
+		args["tagId"] = sql.NullInt64{}
+	} else {
+		args["tagId"] = job.TagId
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return -1, err
+	}
+
+	err = tx.QueryRow(ctx, query, args).Scan(&id)
+	if err != nil {
+		tx.Rollback(ctx)
+		return -1, translateError(err)
+	}
+
+	tx.Commit(ctx)
+	return id, nil
+}
+
+// Start moves pending job or running job which was not updated since staleBefore to running state.
+// Job that is already taken by another worker is reported as not found.
+func (r *JobsRepo) Start(ctx context.Context, jobId int, staleBefore time.Time) (models.Job, error) {
+	var job models.Job
+
+	query := `UPDATE jobs SET status = @running, updated_at = @now WHERE id = @jobId
+	AND (status = @pending OR (status = @running AND updated_at < @staleBefore))
+	RETURNING id, COALESCE(feature_id::bigint, 0), COALESCE(tag_id::bigint, 0), status, total, processed, error, created_at, updated_at`
+	args := pgx.NamedArgs{
+		"jobId":       jobId,
+		"running":     models.JobStatusRunning,
+		"pending":     models.JobStatusPending,
+		"now":         time.Now(),
+		"staleBefore": staleBefore,
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return models.Job{}, err
+	}
+
+	err = tx.QueryRow(ctx, query, args).Scan(&job.ID, &job.FeatureId, &job.TagId, &job.Status, &job.Total,
+		&job.Processed, &job.Error, &job.CreatedAt, &job.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			tx.Rollback(ctx)
+			return models.Job{}, models.NewNotFoundError(fmt.Sprintf("pending job with id=%v not found", jobId))
+		}
+
+		tx.Rollback(ctx)
+		return models.Job{}, translateError(err)
+	}
+
+	tx.Commit(ctx)
+	return job, nil
+}
+
+func (r *JobsRepo) Update(ctx context.Context, job models.Job) error {
+	query := `UPDATE jobs SET status = @status, total = @total, processed = @processed, error = @errorIn,
+    updated_at = @updatedAt WHERE id = @jobId`
+	args := pgx.NamedArgs{
+		"jobId":     job.ID,
+		"status":    job.Status,
+		"total":     job.Total,
+		"processed": job.Processed,
+		"errorIn":   job.Error,
+		"updatedAt": job.UpdatedAt,
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.Exec(ctx, query, args)
+	if err != nil {
+		tx.Rollback(ctx)
+		return translateError(err)
+	}
+
+	if res.RowsAffected() == 0 {
+		tx.Rollback(ctx)
+		return models.NewNotFoundError(fmt.Sprintf("job with id=%v not found", job.ID))
+	}
+
+	tx.Commit(ctx)
+	return nil
+}
+
+func (r *JobsRepo) GetJobById(ctx context.Context, jobId int) (models.Job, error) {
+	var job models.Job
+
+	query := `SELECT id, COALESCE(feature_id::bigint, 0), COALESCE(tag_id::bigint, 0), status, total, processed, error,
+    created_at, updated_at FROM jobs WHERE id = @jobId`
+	args := pgx.NamedArgs{
+		"jobId": jobId,
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return models.Job{}, err
+	}
+
+	err = tx.QueryRow(ctx, query, args).Scan(&job.ID, &job.FeatureId, &job.TagId, &job.Status, &job.Total,
+		&job.Processed, &job.Error, &job.CreatedAt, &job.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			tx.Rollback(ctx)
+			return models.Job{}, models.NewNotFoundError(fmt.Sprintf("job with id=%v not found", jobId))
+		}
+
+		tx.Rollback(ctx)
+		return models.Job{}, translateError(err)
+	}
+
+	tx.Commit(ctx)
+	return job, nil
+}
+
+// GetPendingJobs returns ids of pending jobs and running jobs which were not updated since staleBefore.
+func (r *JobsRepo) GetPendingJobs(ctx context.Context, staleBefore time.Time) ([]int, error) {
+	query := `SELECT id FROM jobs WHERE status = @pending OR (status = @running AND updated_at < @staleBefore)
+	ORDER BY id`
+	args := pgx.NamedArgs{
+		"pending":     models.JobStatusPending,
+		"running":     models.JobStatusRunning,
+		"staleBefore": staleBefore,
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, query, args)
+	if err != nil {
+		tx.Rollback(ctx)
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	jobs := make([]int, 0)
+	for rows.Next() {
+		var id int
+		err := rows.Scan(&id)
+		if err != nil {
+			tx.Rollback(ctx)
+			return nil, translateError(err)
+		}
+
+		jobs = append(jobs, id)
+	}
+
+	tx.Commit(ctx)
+	return jobs, nil
+}
@@ -0,0 +1,160 @@
+package postgresql
+
+import (
+	"avito-test2024-spring/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type SessionsRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewSessionsRepo(db *pgxpool.Pool) *SessionsRepo {
+	return &SessionsRepo{
+		db: db,
+	}
+}
+
+func (r *SessionsRepo) Create(ctx context.Context, session models.Session) (int, error) {
+	var id int
+
+	query := `INSERT INTO sessions (fk_user_id, refresh_token, expires_at, created_at) VALUES (
+    @userId, @refreshToken, @expiresAt, @createdAt) RETURNING id`
+	args := pgx.NamedArgs{
+		"userId":       session.UserId,
+		"refreshToken": session.RefreshToken,
+		"expiresAt":    session.ExpiresAt,
+		"createdAt":    session.CreatedAt,
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return -1, err
+	}
+
+	err = tx.QueryRow(ctx, query, args).Scan(&id)
+	if err != nil {
+		tx.Rollback(ctx)
+		return -1, translateError(err)
+	}
+
+	tx.Commit(ctx)
+	return id, nil
+}
+
+// Update rotates refresh token of the session only if it still has oldRefreshToken, so of concurrent refreshes
+// with the same token only one succeeds and others get not found error.
+func (r *SessionsRepo) Update(ctx context.Context, session models.Session, oldRefreshToken string) error {
+	query := `UPDATE sessions SET refresh_token = @refreshToken, expires_at = @expiresAt
+	WHERE id = @sessionId AND refresh_token = @oldRefreshToken`
+	args := pgx.NamedArgs{
+		"sessionId":       session.ID,
+		"refreshToken":    session.RefreshToken,
+		"oldRefreshToken": oldRefreshToken,
+		"expiresAt":       session.ExpiresAt,
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.Exec(ctx, query, args)
+	if err != nil {
+		tx.Rollback(ctx)
+		return translateError(err)
+	}
+
+	if res.RowsAffected() == 0 {
+		tx.Rollback(ctx)
+		return models.NewNotFoundError(fmt.Sprintf("session with id=%v and such refresh token not found", session.ID))
+	}
+
+	tx.Commit(ctx)
+	return nil
+}
+
+func (r *SessionsRepo) Delete(ctx context.Context, sessionId int) error {
+	query := `DELETE FROM sessions WHERE id=@sessionId`
+	args := pgx.NamedArgs{
+		"sessionId": sessionId,
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.Exec(ctx, query, args)
+	if err != nil {
+		tx.Rollback(ctx)
+		return translateError(err)
+	}
+
+	if res.RowsAffected() == 0 {
+		tx.Rollback(ctx)
+		return models.NewNotFoundError(fmt.Sprintf("session with id=%v not found", sessionId))
+	}
+
+	tx.Commit(ctx)
+	return nil
+}
+
+func (r *SessionsRepo) GetSessionById(ctx context.Context, sessionId int) (models.Session, error) {
+	query := `SELECT id, fk_user_id, refresh_token, expires_at, created_at FROM sessions WHERE id=@sessionId`
+	args := pgx.NamedArgs{
+		"sessionId": sessionId,
+	}
+
+	session, err := r.getSession(ctx, query, args)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Session{}, models.NewNotFoundError(fmt.Sprintf("session with id=%v not found", sessionId))
+		}
+
+		return models.Session{}, err
+	}
+
+	return session, nil
+}
+
+func (r *SessionsRepo) GetSessionByRefreshToken(ctx context.Context, refreshToken string) (models.Session, error) {
+	query := `SELECT id, fk_user_id, refresh_token, expires_at, created_at FROM sessions WHERE refresh_token=@refreshToken`
+	args := pgx.NamedArgs{
+		"refreshToken": refreshToken,
+	}
+
+	session, err := r.getSession(ctx, query, args)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Session{}, models.NewNotFoundError("session with such refresh token not found")
+		}
+
+		return models.Session{}, err
+	}
+
+	return session, nil
+}
+
+func (r *SessionsRepo) getSession(ctx context.Context, query string, args pgx.NamedArgs) (models.Session, error) {
+	var session models.Session
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return models.Session{}, err
+	}
+
+	err = tx.QueryRow(ctx, query, args).Scan(&session.ID, &session.UserId, &session.RefreshToken,
+		&session.ExpiresAt, &session.CreatedAt)
+	if err != nil {
+		tx.Rollback(ctx)
+		return models.Session{}, translateError(err)
+	}
+
+	tx.Commit(ctx)
+	return session, nil
+}
@@ -0,0 +1,125 @@
+package postgresql
+
+import (
+	"avito-test2024-spring/internal/models"
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"time"
+)
+
+type StatsRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewStatsRepo(db *pgxpool.Pool) *StatsRepo {
+	return &StatsRepo{
+		db: db,
+	}
+}
+
+// AddImpressions adds impressions of banners in one query. Impressions of deleted banners are skipped.
+func (r *StatsRepo) AddImpressions(ctx context.Context, impressions []models.BannerStats) error {
+	bannersIds := make([]int, 0, len(impressions))
+	days := make([]string, 0, len(impressions))
+	counts := make([]int, 0, len(impressions))
+	for _, i := range impressions {
+		bannersIds = append(bannersIds, i.BannerId)
+		days = append(days, i.Day)
+		counts = append(counts, i.Impressions)
+	}
+
+	query := `INSERT INTO banners_stats (fk_banner_id, day, impressions)
+	SELECT s.banner_id, s.day, s.impressions
+	FROM unnest(@bannersIds::int[], @days::date[], @counts::bigint[]) AS s(banner_id, day, impressions)
+	JOIN banners ON banners.id = s.banner_id
+	ON CONFLICT (fk_banner_id, day) DO UPDATE SET impressions = banners_stats.impressions + excluded.impressions`
+	args := pgx.NamedArgs{
+		"bannersIds": bannersIds,
+		"days":       days,
+		"counts":     counts,
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, query, args)
+	if err != nil {
+		tx.Rollback(ctx)
+		return translateError(err)
+	}
+
+	tx.Commit(ctx)
+	return nil
+}
+
+func (r *StatsRepo) AddClick(ctx context.Context, bannerId int, day string) error {
+	query := `INSERT INTO banners_stats (fk_banner_id, day, clicks)
+	SELECT id, @day::date, 1 FROM banners WHERE id = @bannerId
+	ON CONFLICT (fk_banner_id, day) DO UPDATE SET clicks = banners_stats.clicks + 1`
+	args := pgx.NamedArgs{
+		"bannerId": bannerId,
+		"day":      day,
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	tag, err := tx.Exec(ctx, query, args)
+	if err != nil {
+		tx.Rollback(ctx)
+		return translateError(err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		tx.Rollback(ctx)
+		return models.NewNotFoundError(fmt.Sprintf("banner with id=%v not found", bannerId))
+	}
+
+	tx.Commit(ctx)
+	return nil
+}
+
+// GetStats returns stats of banner for days from the range, both bounds are included.
+func (r *StatsRepo) GetStats(ctx context.Context, bannerId int, from time.Time, to time.Time) ([]models.BannerStats, error) {
+	query := `SELECT fk_banner_id, to_char(day, 'YYYY-MM-DD'), impressions, clicks FROM banners_stats
+	WHERE fk_banner_id = @bannerId AND day BETWEEN @from::date AND @to::date ORDER BY day`
+	args := pgx.NamedArgs{
+		"bannerId": bannerId,
+		"from":     from.Format(time.DateOnly),
+		"to":       to.Format(time.DateOnly),
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, query, args)
+	if err != nil {
+		tx.Rollback(ctx)
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	stats := make([]models.BannerStats, 0)
+	for rows.Next() {
+		var s models.BannerStats
+		err := rows.Scan(&s.BannerId, &s.Day, &s.Impressions, &s.Clicks)
+		if err != nil {
+			tx.Rollback(ctx)
+			return nil, translateError(err)
+		}
+
+		s.SetCTR()
+		stats = append(stats, s)
+	}
+
+	tx.Commit(ctx)
+	return stats, nil
+}
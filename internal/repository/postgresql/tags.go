@@ -9,6 +9,9 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// tagColumns are columns of tags scanned into models.Tag
+const tagColumns = `id, COALESCE(name, ''), description, owner_team`
+
 type TagsRepo struct {
 	db *pgxpool.Pool
 }
@@ -19,20 +22,26 @@ func NewTagsRepo(db *pgxpool.Pool) *TagsRepo {
 	}
 }
 
-func (r *TagsRepo) Create(ctx context.Context) (int, error) {
+func (r *TagsRepo) Create(ctx context.Context, metadata models.Metadata) (int, error) {
 	var id int
 
-	query := `INSERT INTO tags default values RETURNING id`
+	query := `INSERT INTO tags (name, description, owner_team) VALUES (NULLIF(@name, ''), @description, @ownerTeam)
+	RETURNING id`
+	args := pgx.NamedArgs{
+		"name":        metadata.Name,
+		"description": metadata.Description,
+		"ownerTeam":   metadata.OwnerTeam,
+	}
 
 	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
 		return -1, err
 	}
 
-	err = tx.QueryRow(ctx, query).Scan(&id)
+	err = tx.QueryRow(ctx, query, args).Scan(&id)
 	if err != nil {
 		tx.Rollback(ctx)
-		return -1, err
+		return -1, translateError(err)
 	}
 
 	tx.Commit(ctx)
@@ -50,76 +59,139 @@ func (r *TagsRepo) Delete(ctx context.Context, tagId int) error {
 		return err
 	}
 
-	subQuery := `UPDATE users SET fk_tag_id=null where fk_tag_id = @tagId`
-
-	res, err := tx.Exec(ctx, subQuery, args)
+	// tag is removed from users by cascade of users_tags
+	res, err := tx.Exec(ctx, query, args)
 	if err != nil {
 		tx.Rollback(ctx)
+		return translateError(err)
+	}
+
+	if res.RowsAffected() == 0 {
+		tx.Rollback(ctx)
+		return models.NewNotFoundError(fmt.Sprintf("tag with id=%v not found", tagId))
+	}
+
+	tx.Commit(ctx)
+	return nil
+}
+
+func (r *TagsRepo) Update(ctx context.Context, tag models.Tag) error {
+	query := `UPDATE tags SET name = NULLIF(@name, ''), description = @description, owner_team = @ownerTeam
+	WHERE id = @tagId`
+	args := pgx.NamedArgs{
+		"tagId":       tag.ID,
+		"name":        tag.Name,
+		"description": tag.Description,
+		"ownerTeam":   tag.OwnerTeam,
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
 		return err
 	}
 
-	res, err = tx.Exec(ctx, query, args)
+	res, err := tx.Exec(ctx, query, args)
 	if err != nil {
 		tx.Rollback(ctx)
-		return err
+		return translateError(err)
 	}
 
 	if res.RowsAffected() == 0 {
 		tx.Rollback(ctx)
-		return errors.New(fmt.Sprintf("tag with id=%v not found", tagId))
+		return models.NewNotFoundError(fmt.Sprintf("tag with id=%v not found", tag.ID))
 	}
 
 	tx.Commit(ctx)
 	return nil
 }
 
-func (r *TagsRepo) GetAllTags(ctx context.Context, limit int, offset int) ([]models.Tag, error) {
-	query := `SELECT * FROM tags order by id offset @offsetIn limit @limitIn`
-	args := pgx.NamedArgs{}
-	if limit == 0 {
-		query = `SELECT * FROM tags order by id offset @offsetIn`
-		//args = pgx.NamedArgs{
-		//	"offsetIn": offset,
-		//}
-		args["offsetIn"] = offset
-	} else {
-		//args = pgx.NamedArgs{
-		//	"offsetIn": offset,
-		//	"limitIn":  limit,
-		//}
-		args["offsetIn"] = offset
-		args["limitIn"] = limit
+func (r *TagsRepo) GetTagById(ctx context.Context, tagId int) (models.Tag, error) {
+	query := `SELECT ` + tagColumns + ` FROM tags WHERE id = @tagId`
+	args := pgx.NamedArgs{
+		"tagId": tagId,
+	}
+
+	return r.getTag(ctx, query, args, fmt.Sprintf("tag with id=%v not found", tagId))
+}
+
+func (r *TagsRepo) GetTagByName(ctx context.Context, name string) (models.Tag, error) {
+	query := `SELECT ` + tagColumns + ` FROM tags WHERE name = @name`
+	args := pgx.NamedArgs{
+		"name": name,
 	}
 
+	return r.getTag(ctx, query, args, fmt.Sprintf("tag with name=%q not found", name))
+}
+
+func (r *TagsRepo) getTag(ctx context.Context, query string, args pgx.NamedArgs, notFound string) (models.Tag, error) {
+	var tag models.Tag
+
 	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
-		return nil, err
+		return models.Tag{}, err
 	}
 
-	rows, err := tx.Query(ctx, query, args)
+	err = tx.QueryRow(ctx, query, args).Scan(&tag.ID, &tag.Name, &tag.Description, &tag.OwnerTeam)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			tx.Commit(ctx)
-			return nil, err
+			tx.Rollback(ctx)
+			return models.Tag{}, models.NewNotFoundError(notFound)
 		}
 
 		tx.Rollback(ctx)
-		return nil, err
+		return models.Tag{}, translateError(err)
+	}
+
+	tx.Commit(ctx)
+	return tag, nil
+}
+
+// GetAllTags returns up to limit tags with id greater than afterId and the total number of tags.
+func (r *TagsRepo) GetAllTags(ctx context.Context, afterId int, limit int) ([]models.Tag, int, error) {
+	var total int
+
+	query := `SELECT ` + tagColumns + ` FROM tags WHERE id > @afterId ORDER BY id LIMIT @limitIn`
+	args := pgx.NamedArgs{
+		"afterId": afterId,
+		"limitIn": limit,
+	}
+
+	// page and total count are read from the same snapshot
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = tx.QueryRow(ctx, `SELECT count(*) FROM tags`).Scan(&total)
+	if err != nil {
+		tx.Rollback(ctx)
+		return nil, 0, translateError(err)
+	}
+
+	rows, err := tx.Query(ctx, query, args)
+	if err != nil {
+		tx.Rollback(ctx)
+		return nil, 0, translateError(err)
 	}
 	defer rows.Close()
 
 	tags := make([]models.Tag, 0)
 	for rows.Next() {
 		tag := models.Tag{}
-		err := rows.Scan(&tag.ID)
+		err := rows.Scan(&tag.ID, &tag.Name, &tag.Description, &tag.OwnerTeam)
 		if err != nil {
 			tx.Rollback(ctx)
-			return nil, err
+			return nil, 0, translateError(err)
 		}
 
 		tags = append(tags, tag)
 	}
 
+	if err := rows.Err(); err != nil {
+		tx.Rollback(ctx)
+		return nil, 0, translateError(err)
+	}
+
 	tx.Commit(ctx)
-	return tags, nil
+	return tags, total, nil
 }
@@ -3,13 +3,16 @@ package postgresql
 import (
 	"avito-test2024-spring/internal/models"
 	"context"
-	"database/sql"
 	"errors"
 	"fmt"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// userTagsColumn selects ids of user tags ordered by priority as an array.
+const userTagsColumn = `ARRAY(SELECT ut.fk_tag_id FROM users_tags ut WHERE ut.fk_user_id = users.id` +
+	` ORDER BY ut.priority) AS tags_ids`
+
 type UsersRepo struct {
 	db *pgxpool.Pool
 }
@@ -23,18 +26,9 @@ func NewUsersRepo(db *pgxpool.Pool) *UsersRepo {
 func (r *UsersRepo) Create(ctx context.Context, user models.User) (int, error) {
 	var id int
 
-	query := `INSERT INTO users (is_admin, fk_tag_id) values (@isAdmin, @tagId) returning id`
-	args := pgx.NamedArgs{}
-	if user.TagId == 0 {
-		query = `INSERT INTO users (is_admin) values (@isAdmin) returning id`
-		args = pgx.NamedArgs{
-			"isAdmin": user.IsAdmin,
-		}
-	} else {
-		args = pgx.NamedArgs{
-			"isAdmin": user.IsAdmin,
-			"tagId":   user.TagId,
-		}
+	query := `INSERT INTO users (is_admin) values (@isAdmin) returning id`
+	args := pgx.NamedArgs{
+		"isAdmin": user.IsAdmin,
 	}
 
 	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
@@ -45,7 +39,13 @@ func (r *UsersRepo) Create(ctx context.Context, user models.User) (int, error) {
 	err = tx.QueryRow(ctx, query, args).Scan(&id)
 	if err != nil {
 		tx.Rollback(ctx)
-		return -1, err
+		return -1, translateError(err)
+	}
+
+	err = r.setUserTags(ctx, tx, id, user.TagsIds)
+	if err != nil {
+		tx.Rollback(ctx)
+		return -1, translateError(err)
 	}
 
 	tx.Commit(ctx)
@@ -53,17 +53,12 @@ func (r *UsersRepo) Create(ctx context.Context, user models.User) (int, error) {
 }
 
 func (r *UsersRepo) Update(ctx context.Context, user models.User) error {
-	query := `UPDATE users SET fk_tag_id = @tagId, is_admin = @isAdmin WHERE id = @userId`
-	args := pgx.NamedArgs{}
-	if user.TagId == 0 {
-		args["tagId"] = sql.NullInt64{}
-	} else {
-		args["tagId"] = user.TagId
+	query := `UPDATE users SET is_admin = @isAdmin WHERE id = @userId`
+	args := pgx.NamedArgs{
+		"isAdmin": user.IsAdmin,
+		"userId":  user.Id,
 	}
 
-	args["isAdmin"] = user.IsAdmin
-	args["userId"] = user.Id
-
 	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
 		return err
@@ -72,18 +67,47 @@ func (r *UsersRepo) Update(ctx context.Context, user models.User) error {
 	res, err := tx.Exec(ctx, query, args)
 	if err != nil {
 		tx.Rollback(ctx)
-		return err
+		return translateError(err)
 	}
 
 	if res.RowsAffected() == 0 {
 		tx.Rollback(ctx)
-		return errors.New(fmt.Sprintf("user with id=%v not found", user.Id))
+		return models.NewNotFoundError(fmt.Sprintf("user with id=%v not found", user.Id))
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM users_tags WHERE fk_user_id = @userId`, args)
+	if err != nil {
+		tx.Rollback(ctx)
+		return translateError(err)
+	}
+
+	err = r.setUserTags(ctx, tx, user.Id, user.TagsIds)
+	if err != nil {
+		tx.Rollback(ctx)
+		return translateError(err)
 	}
 
 	tx.Commit(ctx)
 	return nil
 }
 
+// setUserTags inserts tags of the user, priority of the tag is its position in tagsIds.
+func (r *UsersRepo) setUserTags(ctx context.Context, tx pgx.Tx, userId int, tagsIds []int) error {
+	if len(tagsIds) == 0 {
+		return nil
+	}
+
+	query := `INSERT INTO users_tags (fk_user_id, fk_tag_id, priority)
+	SELECT @userId, tag.id, tag.priority - 1 FROM unnest(@tagsIds::int[]) WITH ORDINALITY AS tag(id, priority)`
+	args := pgx.NamedArgs{
+		"userId":  userId,
+		"tagsIds": tagsIds,
+	}
+
+	_, err := tx.Exec(ctx, query, args)
+	return err
+}
+
 func (r *UsersRepo) Delete(ctx context.Context, userId int) error {
 	query := `DELETE FROM users WHERE id=@userId`
 	args := pgx.NamedArgs{
@@ -98,12 +122,12 @@ func (r *UsersRepo) Delete(ctx context.Context, userId int) error {
 	res, err := tx.Exec(ctx, query, args)
 	if err != nil {
 		tx.Rollback(ctx)
-		return err
+		return translateError(err)
 	}
 
 	if res.RowsAffected() == 0 {
 		tx.Rollback(ctx)
-		return errors.New(fmt.Sprintf("user with id=%v not found", userId))
+		return models.NewNotFoundError(fmt.Sprintf("user with id=%v not found", userId))
 	}
 
 	tx.Commit(ctx)
@@ -113,7 +137,7 @@ func (r *UsersRepo) Delete(ctx context.Context, userId int) error {
 func (r *UsersRepo) GetUserById(ctx context.Context, userId int) (models.User, error) {
 	var user models.User
 
-	query := `SELECT id, COALESCE(fk_tag_id::bigint, 0), is_admin FROM users where id=@userId`
+	query := `SELECT id, ` + userTagsColumn + `, is_admin FROM users where id=@userId`
 	args := pgx.NamedArgs{
 		"userId": userId,
 	}
@@ -123,71 +147,79 @@ func (r *UsersRepo) GetUserById(ctx context.Context, userId int) (models.User, e
 		return models.User{}, err
 	}
 
-	err = tx.QueryRow(ctx, query, args).Scan(&user.Id, &user.TagId, &user.IsAdmin)
+	err = tx.QueryRow(ctx, query, args).Scan(&user.Id, &user.TagsIds, &user.IsAdmin)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			tx.Rollback(ctx)
-			return models.User{}, errors.New(fmt.Sprintf("user with id=%v not found", userId))
+			return models.User{}, models.NewNotFoundError(fmt.Sprintf("user with id=%v not found", userId))
 		}
 
 		tx.Rollback(ctx)
-		return models.User{}, err
+		return models.User{}, translateError(err)
 	}
 
 	tx.Commit(ctx)
 	return user, nil
 }
 
-func (r *UsersRepo) GetAllUsers(ctx context.Context, tagId int, limit int, offset int) ([]models.User, error) {
-	query := `SELECT id, COALESCE(fk_tag_id::bigint, 0), is_admin FROM users`
-	args := pgx.NamedArgs{}
+// GetAllUsers returns up to limit users with id greater than afterId and the total number of users matching filter.
+// If tagsIds is not empty, only users having any of these tags are returned.
+func (r *UsersRepo) GetAllUsers(ctx context.Context, tagsIds []int, afterId int, limit int) ([]models.User, int, error) {
+	var total int
 
-	if tagId != 0 {
-		query += ` WHERE fk_tag_id=@tagId`
-		args["tagId"] = tagId
+	filter := ``
+	args := pgx.NamedArgs{
+		"afterId": afterId,
+		"limitIn": limit,
+	}
+
+	if len(tagsIds) > 0 {
+		filter = ` WHERE EXISTS (SELECT 1 FROM users_tags ut WHERE ut.fk_user_id = users.id` +
+			` AND ut.fk_tag_id = ANY(@tagsIds))`
+		args["tagsIds"] = tagsIds
 	}
 
-	query += ` ORDER BY id`
+	countQuery := `SELECT count(*) FROM users` + filter
 
-	if offset != 0 {
-		query += ` OFFSET @offsetIn`
-		args["offsetIn"] = offset
+	query := `SELECT id, ` + userTagsColumn + `, is_admin FROM users` + filter
+	if filter == `` {
+		query += ` WHERE id > @afterId`
+	} else {
+		query += ` AND id > @afterId`
 	}
+	query += ` ORDER BY id LIMIT @limitIn`
 
-	if limit != 0 {
-		query += ` LIMIT @limitIn`
-		args["limitIn"] = limit
+	// page and total count are read from the same snapshot
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, 0, err
 	}
 
-	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	err = tx.QueryRow(ctx, countQuery, args).Scan(&total)
 	if err != nil {
-		return nil, err
+		tx.Rollback(ctx)
+		return nil, 0, translateError(err)
 	}
 
 	rows, err := tx.Query(ctx, query, args)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			tx.Commit(ctx)
-			return nil, err
-		}
-
 		tx.Rollback(ctx)
-		return nil, err
+		return nil, 0, translateError(err)
 	}
 	defer rows.Close()
 
 	users := make([]models.User, 0)
 	for rows.Next() {
 		user := models.User{}
-		err := rows.Scan(&user.Id, &user.TagId, &user.IsAdmin)
+		err := rows.Scan(&user.Id, &user.TagsIds, &user.IsAdmin)
 		if err != nil {
 			tx.Rollback(ctx)
-			return nil, err
+			return nil, 0, translateError(err)
 		}
 
 		users = append(users, user)
 	}
 
 	tx.Commit(ctx)
-	return users, nil
+	return users, total, nil
 }
@@ -0,0 +1,172 @@
+package postgresql
+
+import (
+	"avito-test2024-spring/internal/models"
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"time"
+)
+
+// variantLiveColumn tells if banner of variant joined to banners_variants can be shown to users at @now
+var variantLiveColumn = `(banners.is_active AND ` + bannerWindowConditions[models.BannerWindowLive] + `)`
+
+type VariantsRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewVariantsRepo(db *pgxpool.Pool) *VariantsRepo {
+	return &VariantsRepo{
+		db: db,
+	}
+}
+
+// Set replaces all variants of experiment on feature and tag.
+func (r *VariantsRepo) Set(ctx context.Context, featureId int, tagId int, variants []models.Variant) error {
+	args := pgx.NamedArgs{
+		"featureId": featureId,
+		"tagId":     tagId,
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM banners_variants WHERE fk_feature_id = @featureId AND fk_tag_id = @tagId`, args)
+	if err != nil {
+		tx.Rollback(ctx)
+		return translateError(err)
+	}
+
+	for _, v := range variants {
+		args["bannerId"] = v.BannerId
+		args["weight"] = v.Weight
+
+		_, err = tx.Exec(ctx, `INSERT INTO banners_variants (fk_feature_id, fk_tag_id, fk_banner_id, weight)
+		VALUES (@featureId, @tagId, @bannerId, @weight)`, args)
+		if err != nil {
+			tx.Rollback(ctx)
+			return translateError(err)
+		}
+	}
+
+	tx.Commit(ctx)
+	return nil
+}
+
+func (r *VariantsRepo) Delete(ctx context.Context, featureId int, tagId int) error {
+	query := `DELETE FROM banners_variants WHERE fk_feature_id = @featureId AND fk_tag_id = @tagId`
+	args := pgx.NamedArgs{
+		"featureId": featureId,
+		"tagId":     tagId,
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	tag, err := tx.Exec(ctx, query, args)
+	if err != nil {
+		tx.Rollback(ctx)
+		return translateError(err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		tx.Rollback(ctx)
+		return models.NewNotFoundError(fmt.Sprintf("experiment with feature_id=%v and tag_id=%v not found", featureId, tagId))
+	}
+
+	tx.Commit(ctx)
+	return nil
+}
+
+// GetVariants returns all variants of experiment on feature and tag ordered by banner id. Variants whose banners
+// are active and inside activation window right now are marked live.
+func (r *VariantsRepo) GetVariants(ctx context.Context, featureId int, tagId int) ([]models.Variant, error) {
+	query := `SELECT banners_variants.fk_banner_id, banners_variants.weight, ` + variantLiveColumn + `
+	FROM banners_variants JOIN banners ON banners.id = banners_variants.fk_banner_id
+	WHERE banners_variants.fk_feature_id = @featureId AND banners_variants.fk_tag_id = @tagId
+	ORDER BY banners_variants.fk_banner_id`
+	args := pgx.NamedArgs{
+		"featureId": featureId,
+		"tagId":     tagId,
+		"now":       time.Now(),
+	}
+
+	return r.getVariants(ctx, query, args)
+}
+
+// GetFeaturesVariants returns variants of experiments on the tag and each of features with one query, like
+// GetVariants does for one feature. Features without experiment are absent from the result.
+func (r *VariantsRepo) GetFeaturesVariants(ctx context.Context, tagId int,
+	featuresIds []int) (map[int][]models.Variant, error) {
+	query := `SELECT banners_variants.fk_feature_id, banners_variants.fk_banner_id, banners_variants.weight, ` +
+		variantLiveColumn + ` FROM banners_variants JOIN banners ON banners.id = banners_variants.fk_banner_id
+	WHERE banners_variants.fk_tag_id = @tagId AND banners_variants.fk_feature_id = ANY(@featuresIds)
+	ORDER BY banners_variants.fk_feature_id, banners_variants.fk_banner_id`
+	args := pgx.NamedArgs{
+		"tagId":       tagId,
+		"featuresIds": featuresIds,
+		"now":         time.Now(),
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, query, args)
+	if err != nil {
+		tx.Rollback(ctx)
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	variants := make(map[int][]models.Variant)
+	for rows.Next() {
+		var featureId int
+		var v models.Variant
+		err := rows.Scan(&featureId, &v.BannerId, &v.Weight, &v.Live)
+		if err != nil {
+			tx.Rollback(ctx)
+			return nil, translateError(err)
+		}
+
+		variants[featureId] = append(variants[featureId], v)
+	}
+
+	tx.Commit(ctx)
+	return variants, nil
+}
+
+func (r *VariantsRepo) getVariants(ctx context.Context, query string, args pgx.NamedArgs) ([]models.Variant, error) {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, query, args)
+	if err != nil {
+		tx.Rollback(ctx)
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	variants := make([]models.Variant, 0)
+	for rows.Next() {
+		var v models.Variant
+		err := rows.Scan(&v.BannerId, &v.Weight, &v.Live)
+		if err != nil {
+			tx.Rollback(ctx)
+			return nil, translateError(err)
+		}
+
+		variants = append(variants, v)
+	}
+
+	tx.Commit(ctx)
+	return variants, nil
+}
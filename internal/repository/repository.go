@@ -4,28 +4,53 @@ import (
 	"avito-test2024-spring/internal/models"
 	"avito-test2024-spring/internal/repository/postgresql"
 	"context"
+	"encoding/json"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"time"
 )
 
 type Banners interface {
 	Create(ctx context.Context, banner models.AdminBanner) (int, error)
-	Update(ctx context.Context, banner models.AdminBanner, toDel []int) error
+	Update(ctx context.Context, banner models.AdminBanner, toDel []int, versionsLimit int) error
 	Delete(ctx context.Context, bannerId int) error
 	GetBannerByID(ctx context.Context, bannerId int) (models.AdminBanner, error)
-	GetUserBanner(ctx context.Context, tagId int, featureId int) (models.Banner, int, error)
-	GetAllBanners(ctx context.Context, featureId int, tagId int, limit int, offset int) ([]models.AdminBanner, error)
+	GetUserBanner(ctx context.Context, featureId int, tagId int) (models.AdminBanner, error)
+	GetLiveBanner(ctx context.Context, bannerId int) (models.AdminBanner, error)
+	GetUserBanners(ctx context.Context, tagId int, featuresIds []int, bannersIds []int) ([]models.AdminBanner, error)
+	GetAllBanners(ctx context.Context, filter models.BannerFilter, cursor models.Cursor, limit int) ([]models.AdminBanner, int, error)
+	GetBannerVersions(ctx context.Context, bannerId int) ([]models.BannerVersion, error)
+	ActivateVersion(ctx context.Context, bannerId int, version int, versionsLimit int) error
+	CountByFeatureTag(ctx context.Context, featureId int, tagId int) (int, error)
+	DeleteByFeatureTag(ctx context.Context, featureId int, tagId int, limit int) ([]models.AdminBanner, error)
+	GetWindowBoundaryBanners(ctx context.Context, from time.Time, to time.Time) ([]int, error)
+	GetConflicts(ctx context.Context, bannerId int, featureId int, tagsIds []int) ([]models.BannerConflict, error)
 }
 
 type Tags interface {
-	Create(ctx context.Context) (int, error)
+	Create(ctx context.Context, metadata models.Metadata) (int, error)
+	Update(ctx context.Context, tag models.Tag) error
 	Delete(ctx context.Context, tagId int) error
-	GetAllTags(ctx context.Context, limit int, offset int) ([]models.Tag, error)
+	GetTagById(ctx context.Context, tagId int) (models.Tag, error)
+	GetTagByName(ctx context.Context, name string) (models.Tag, error)
+	GetAllTags(ctx context.Context, afterId int, limit int) ([]models.Tag, int, error)
 }
 
 type Features interface {
-	Create(ctx context.Context) (int, error)
+	Create(ctx context.Context, metadata models.Metadata) (int, error)
+	Update(ctx context.Context, feature models.Feature) error
 	Delete(ctx context.Context, featureId int) error
-	GetAllFeatures(ctx context.Context, limit int, offset int) ([]models.Feature, error)
+	GetFeatureById(ctx context.Context, featureId int) (models.Feature, error)
+	GetFeatureByName(ctx context.Context, name string) (models.Feature, error)
+	GetAllFeatures(ctx context.Context, afterId int, limit int) ([]models.Feature, int, error)
+	SetContentSchema(ctx context.Context, featureId int, schema json.RawMessage) error
+	GetContentSchema(ctx context.Context, featureId int) (json.RawMessage, error)
+}
+
+type Variants interface {
+	Set(ctx context.Context, featureId int, tagId int, variants []models.Variant) error
+	Delete(ctx context.Context, featureId int, tagId int) error
+	GetVariants(ctx context.Context, featureId int, tagId int) ([]models.Variant, error)
+	GetFeaturesVariants(ctx context.Context, tagId int, featuresIds []int) (map[int][]models.Variant, error)
 }
 
 type Users interface {
@@ -33,14 +58,40 @@ type Users interface {
 	Update(ctx context.Context, user models.User) error
 	Delete(ctx context.Context, userId int) error
 	GetUserById(ctx context.Context, userId int) (models.User, error)
-	GetAllUsers(ctx context.Context, tagId int, limit int, offset int) ([]models.User, error)
+	GetAllUsers(ctx context.Context, tagsIds []int, afterId int, limit int) ([]models.User, int, error)
+}
+
+type Sessions interface {
+	Create(ctx context.Context, session models.Session) (int, error)
+	Update(ctx context.Context, session models.Session, oldRefreshToken string) error
+	Delete(ctx context.Context, sessionId int) error
+	GetSessionById(ctx context.Context, sessionId int) (models.Session, error)
+	GetSessionByRefreshToken(ctx context.Context, refreshToken string) (models.Session, error)
+}
+
+type Jobs interface {
+	Create(ctx context.Context, job models.Job) (int, error)
+	Start(ctx context.Context, jobId int, staleBefore time.Time) (models.Job, error)
+	Update(ctx context.Context, job models.Job) error
+	GetJobById(ctx context.Context, jobId int) (models.Job, error)
+	GetPendingJobs(ctx context.Context, staleBefore time.Time) ([]int, error)
+}
+
+type Stats interface {
+	AddImpressions(ctx context.Context, impressions []models.BannerStats) error
+	AddClick(ctx context.Context, bannerId int, day string) error
+	GetStats(ctx context.Context, bannerId int, from time.Time, to time.Time) ([]models.BannerStats, error)
 }
 
 type Repositories struct {
 	Banners  Banners
 	Tags     Tags
 	Features Features
+	Variants Variants
 	Users    Users
+	Sessions Sessions
+	Jobs     Jobs
+	Stats    Stats
 }
 
 func NewRepositories(db *pgxpool.Pool) *Repositories {
@@ -48,6 +99,10 @@ func NewRepositories(db *pgxpool.Pool) *Repositories {
 		Banners:  postgresql.NewBannersRepo(db),
 		Tags:     postgresql.NewTagsRepo(db),
 		Features: postgresql.NewFeaturesRepo(db),
+		Variants: postgresql.NewVariantsRepo(db),
 		Users:    postgresql.NewUsersRepo(db),
+		Sessions: postgresql.NewSessionsRepo(db),
+		Jobs:     postgresql.NewJobsRepo(db),
+		Stats:    postgresql.NewStatsRepo(db),
 	}
 }
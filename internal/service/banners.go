@@ -1,89 +1,164 @@
 package service
 
 import (
+	"avito-test2024-spring/internal/config"
 	"avito-test2024-spring/internal/models"
 	"avito-test2024-spring/internal/repository"
 	"avito-test2024-spring/pkg/cache"
+	"avito-test2024-spring/pkg/metrics"
+	"avito-test2024-spring/pkg/pubsub"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/rs/zerolog"
+	"golang.org/x/sync/singleflight"
 	"io"
-	"net/http"
+	"math"
+	"math/rand"
+	"slices"
 	"strconv"
-	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// maxUserBannersFeatures limits number of features in one GetUserBanners call
+const maxUserBannersFeatures = 100
+
+const (
+	CacheUpdateEvict        = "evict"
+	CacheUpdateWriteThrough = "write_through"
+)
+
 type BannersService struct {
-	repo  repository.Banners
-	cache cache.Cache
+	repo         repository.Banners
+	featuresRepo repository.Features
+	variantsRepo repository.Variants
+	stats        Stats
+	cache        cache.Cache
+	events       pubsub.PubSub
+	logger       zerolog.Logger
+
+	versionsLimit     int
+	cacheUpdate       string
+	schedulerInterval time.Duration
+
+	// loads of user banner from DB are deduplicated per tag_id:feature_id key
+	loads            singleflight.Group
+	earlyRefreshBeta float64
+	// duration of the last user banner load from DB, used to decide on early refresh
+	lastLoadDuration atomic.Int64
+
+	// live variants of A/B experiments per tag_id:feature_id key, loaded from DB at most once per variantsCacheTTL
+	variants         map[string]loadedVariants
+	variantsMu       sync.Mutex
+	variantsCacheTTL time.Duration
+
+	pager pager
+}
+
+type loadedVariants struct {
+	variants []models.Variant
+	loadedAt time.Time
 }
 
-func NewBannersService(repo repository.Banners, cache cache.Cache) *BannersService {
+func NewBannersService(repo repository.Banners, featuresRepo repository.Features, variantsRepo repository.Variants,
+	stats Stats, cache cache.Cache, events pubsub.PubSub, logger zerolog.Logger, cfg config.BannersConfig,
+	pagination config.PaginationConfig) *BannersService {
 	return &BannersService{
-		repo:  repo,
-		cache: cache,
+		repo:              repo,
+		featuresRepo:      featuresRepo,
+		variantsRepo:      variantsRepo,
+		stats:             stats,
+		cache:             cache,
+		events:            events,
+		logger:            logger,
+		versionsLimit:     cfg.VersionsLimit,
+		cacheUpdate:       cfg.CacheUpdate,
+		schedulerInterval: cfg.SchedulerInterval,
+		earlyRefreshBeta:  cfg.EarlyRefreshBeta,
+		variants:          make(map[string]loadedVariants),
+		variantsCacheTTL:  cfg.VariantsCacheTTL,
+		pager:             newPager(pagination),
 	}
 }
 
 type BannerAddInput struct {
-	Title    string
-	Text     string
-	URL      string
+	Content  json.RawMessage
 	Tags     []int
 	Feature  int
 	IsActive bool
+
+	ActiveFrom  *time.Time
+	ActiveUntil *time.Time
+
+	// DryRun only validates banner and checks conflicts, nothing is written
+	DryRun bool
 }
 
-func (s *BannersService) AddBanner(ctx context.Context, input BannerAddInput) (int, models.ErrService) {
+func (s *BannersService) AddBanner(ctx context.Context, input BannerAddInput) (int, error) {
 	var banner models.AdminBanner
 
-	bannerContent := models.Banner{
-		Title: input.Title,
-		Text:  input.Text,
-		URL:   input.URL,
-	}
-
-	err := bannerContent.ValidateBanner()
+	err := models.ValidateBannerContent(input.Content)
 	if err != nil {
-		return -1, models.NewErrorService(http.StatusBadRequest, err.Error())
+		return -1, models.NewValidationError(err.Error())
 	}
 
-	banner.Content = bannerContent
+	banner.Content = input.Content
 
 	err = banner.ValidateAndSetFeature(input.Feature)
 	if err != nil {
-		return -1, models.NewErrorService(http.StatusBadRequest, err.Error())
+		return -1, models.NewValidationError(err.Error())
+	}
+
+	err = s.validateContentBySchema(ctx, banner.Content, banner.Feature.ID)
+	if err != nil {
+		return -1, err
 	}
 
 	err = banner.ValidateAndSetTags(input.Tags)
 	if err != nil {
-		return -1, models.NewErrorService(http.StatusBadRequest, err.Error())
+		return -1, models.NewValidationError(err.Error())
 	}
 
 	banner.IsActive = input.IsActive
 
+	err = banner.ValidateAndSetActiveWindow(input.ActiveFrom, input.ActiveUntil)
+	if err != nil {
+		return -1, models.NewValidationError(err.Error())
+	}
+
+	err = s.checkConflicts(ctx, banner)
+	if err != nil {
+		return -1, err
+	}
+
+	if input.DryRun {
+		return -1, nil
+	}
+
 	banner.CreatedAt = time.Now()
 	banner.UpdatedAt = time.Now()
 
 	bannerId, err := s.repo.Create(ctx, banner)
 	if err != nil {
-		return -1, models.NewErrorService(http.StatusInternalServerError, err.Error())
+		return -1, err
 	}
 
-	return bannerId, models.ErrService{}
-}
+	s.publish(models.NewBannerEvent(models.BannerEventCreated, bannerId, banner))
 
-type bannersUpdateContent struct {
-	Title string `json:"title,omitempty"`
-	Text  string `json:"text,omitempty"`
-	URL   string `json:"url,omitempty"`
+	return bannerId, nil
 }
 
 type bannersUpdateInput struct {
-	Tags     []int                `json:"tags_ids,omitempty"`
-	Feature  int                  `json:"feature_id,omitempty"`
-	Content  bannersUpdateContent `json:"content,omitempty"`
-	IsActive bool                 `json:"is_active,omitempty"`
+	Tags     []int           `json:"tags_ids,omitempty"`
+	Feature  int             `json:"feature_id,omitempty"`
+	Content  json.RawMessage `json:"content,omitempty"`
+	IsActive bool            `json:"is_active,omitempty"`
+	// explicit null removes the bound of activation window
+	ActiveFrom  *time.Time `json:"active_from,omitempty"`
+	ActiveUntil *time.Time `json:"active_until,omitempty"`
 }
 
 func (i *bannersUpdateInput) setTags(tags []models.Tag) {
@@ -92,63 +167,57 @@ func (i *bannersUpdateInput) setTags(tags []models.Tag) {
 	}
 }
 
-func (s *BannersService) UpdateBanner(ctx context.Context) models.ErrService {
+func (s *BannersService) UpdateBanner(ctx context.Context) error {
 	bannerId, err := strconv.Atoi(ctx.Value("banner_id").(string))
 	if err != nil {
-		return models.NewErrorService(http.StatusBadRequest, err.Error())
+		return models.NewValidationError(err.Error())
 	}
 
 	bannerOld, err := s.repo.GetBannerByID(ctx, bannerId)
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			return models.NewErrorService(http.StatusNotFound, err.Error())
-		}
-		return models.NewErrorService(http.StatusInternalServerError, err.Error())
+		return err
 	}
 
 	bannerInput := bannersUpdateInput{
-		Feature: bannerOld.Feature.ID,
-		Content: bannersUpdateContent{
-			Title: bannerOld.Content.Title,
-			Text:  bannerOld.Content.Text,
-			URL:   bannerOld.Content.URL,
-		},
-		IsActive: bannerOld.IsActive,
+		Feature:     bannerOld.Feature.ID,
+		Content:     bannerOld.Content,
+		IsActive:    bannerOld.IsActive,
+		ActiveFrom:  bannerOld.ActiveFrom,
+		ActiveUntil: bannerOld.ActiveUntil,
 	}
 
 	if err := json.NewDecoder(ctx.Value("request_body").(io.Reader)).Decode(&bannerInput); err != nil {
-		return models.NewErrorService(http.StatusInternalServerError, err.Error())
+		return models.NewValidationError(err.Error())
 	}
 
 	var banner models.AdminBanner
 
-	bannerContent := models.Banner{
-		Title: bannerInput.Content.Title,
-		Text:  bannerInput.Content.Text,
-		URL:   bannerInput.Content.URL,
-	}
-
-	err = bannerContent.ValidateBanner()
+	err = models.ValidateBannerContent(bannerInput.Content)
 	if err != nil {
-		return models.NewErrorService(http.StatusBadRequest, err.Error())
+		return models.NewValidationError(err.Error())
 	}
 
-	banner.Content = bannerContent
+	banner.Content = bannerInput.Content
 
 	if bannerInput.Feature == 0 {
 		banner.Feature.ID = bannerOld.Feature.ID
 	} else {
 		err = banner.ValidateAndSetFeature(bannerInput.Feature)
 		if err != nil {
-			return models.NewErrorService(http.StatusBadRequest, err.Error())
+			return models.NewValidationError(err.Error())
 		}
 	}
 
+	err = s.validateContentBySchema(ctx, banner.Content, banner.Feature.ID)
+	if err != nil {
+		return err
+	}
+
 	banner.Tags = bannerOld.Tags
 
 	toDel, err := banner.ValidateAndUpdateTags(bannerInput.Tags)
 	if err != nil {
-		return models.NewErrorService(http.StatusBadRequest, err.Error())
+		return models.NewValidationError(err.Error())
 	}
 
 	banner.CreatedAt = bannerOld.CreatedAt
@@ -156,122 +225,886 @@ func (s *BannersService) UpdateBanner(ctx context.Context) models.ErrService {
 	banner.ID = bannerId
 	banner.IsActive = bannerInput.IsActive
 
-	err = s.repo.Update(ctx, banner, toDel)
+	err = banner.ValidateAndSetActiveWindow(bannerInput.ActiveFrom, bannerInput.ActiveUntil)
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			return models.NewErrorService(http.StatusNotFound, err.Error())
-		}
-		return models.NewErrorService(http.StatusInternalServerError, err.Error())
+		return models.NewValidationError(err.Error())
+	}
+
+	err = s.checkConflicts(ctx, banner)
+	if err != nil {
+		return err
 	}
 
-	if (bannerOld.IsActive != banner.IsActive) && (banner.IsActive == false) {
-		err = s.cache.Delete(banner.ID)
+	if dryRun, _ := ctx.Value("dry_run").(bool); dryRun {
+		return nil
+	}
+
+	err = s.repo.Update(ctx, banner, toDel, s.versionsLimit)
+	if err != nil {
+		return err
+	}
+
+	err = s.refreshCache(banner)
+	if err != nil {
+		return err
+	}
+
+	eventType := models.BannerEventUpdated
+	if banner.IsActive != bannerOld.IsActive || !banner.SameActiveWindow(bannerOld) {
+		eventType = models.BannerEventToggled
+	}
+	s.publish(models.NewBannerEvent(eventType, banner.ID, bannerOld, banner))
+
+	return nil
+}
+
+// checkConflicts returns BannerConflictsError if some pairs of banner feature and tags are taken by other banners.
+func (s *BannersService) checkConflicts(ctx context.Context, banner models.AdminBanner) error {
+	if banner.Feature.ID == 0 || len(banner.Tags) == 0 {
+		return nil
+	}
+
+	tagsIds := make([]int, 0, len(banner.Tags))
+	for _, t := range banner.Tags {
+		tagsIds = append(tagsIds, t.ID)
+	}
+
+	conflicts, err := s.repo.GetConflicts(ctx, banner.ID, banner.Feature.ID, tagsIds)
+	if err != nil {
+		return err
+	}
+
+	if len(conflicts) > 0 {
+		return &models.BannerConflictsError{Conflicts: conflicts}
+	}
+
+	return nil
+}
+
+// refreshCache drops all cached entries of the updated banner, so keys of removed tags and old feature are
+// not served anymore. In write-through mode entries for the new tags and feature are written right away.
+func (s *BannersService) refreshCache(banner models.AdminBanner) error {
+	err := s.cache.Delete(banner.ID)
+	if err != nil {
+		return err
+	}
+
+	if s.cacheUpdate != CacheUpdateWriteThrough || !banner.IsActive || banner.Feature.ID == 0 ||
+		!banner.IsLive(time.Now()) {
+		return nil
+	}
+
+	for _, tag := range banner.Tags {
+		err = s.cache.Set(banner.Content, tag.ID, banner.Feature.ID, 0, banner.ID, cacheExpiresAt(banner))
 		if err != nil {
-			return models.NewErrorService(http.StatusInternalServerError, err.Error())
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *BannersService) validateContentBySchema(ctx context.Context, content models.Banner, featureId int) error {
+	if featureId == 0 {
+		return nil
+	}
+
+	schema, err := s.featuresRepo.GetContentSchema(ctx, featureId)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return models.NewValidationError(err.Error())
+		}
+		return err
+	}
+
+	err = models.ValidateBannerContentBySchema(content, schema)
+	if err != nil {
+		return models.NewValidationError(err.Error())
+	}
+
+	return nil
+}
+
+func (s *BannersService) GetBannerVersions(ctx context.Context, bannerId int) ([]models.BannerVersion, error) {
+	if bannerId <= 0 {
+		return nil, models.NewValidationError("banner id must be greater than 0")
+	}
+
+	versions, err := s.repo.GetBannerVersions(ctx, bannerId)
+	if err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+func (s *BannersService) ActivateBannerVersion(ctx context.Context, bannerId int, version int) error {
+	if bannerId <= 0 {
+		return models.NewValidationError("banner id must be greater than 0")
+	}
+
+	if version <= 0 {
+		return models.NewValidationError("version must be greater than 0")
+	}
+
+	bannerOld, err := s.repo.GetBannerByID(ctx, bannerId)
+	if err != nil {
+		return err
+	}
+
+	err = s.repo.ActivateVersion(ctx, bannerId, version, s.versionsLimit)
+	if err != nil {
+		return err
+	}
+
+	err = s.cache.Delete(bannerId)
+	if err != nil {
+		return err
+	}
+
+	// version may have other feature and tags, subscribers of the old ones are notified anyway
+	eventType := models.BannerEventUpdated
+	banners := []models.AdminBanner{bannerOld}
+	if banner, err := s.repo.GetBannerByID(ctx, bannerId); err == nil {
+		banners = append(banners, banner)
+
+		if banner.IsActive != bannerOld.IsActive || !banner.SameActiveWindow(bannerOld) {
+			eventType = models.BannerEventToggled
 		}
 	}
+	s.publish(models.NewBannerEvent(eventType, bannerId, banners...))
 
-	return models.ErrService{}
+	return nil
 }
 
-func (s *BannersService) DeleteBanner(ctx context.Context, bannerId int) models.ErrService {
+func (s *BannersService) DeleteBanner(ctx context.Context, bannerId int) error {
 	if bannerId <= 0 {
-		return models.NewErrorService(http.StatusBadRequest, "banner id must be greater than 0")
+		return models.NewValidationError("banner id must be greater than 0")
+	}
+
+	// feature and tags of the banner are needed for the event after it is deleted
+	banner, err := s.repo.GetBannerByID(ctx, bannerId)
+	if err != nil {
+		return err
 	}
 
-	err := s.repo.Delete(ctx, bannerId)
+	err = s.repo.Delete(ctx, bannerId)
 	if err != nil {
-		return models.NewErrorService(http.StatusInternalServerError, err.Error())
+		return err
 	}
 
 	err = s.cache.Delete(bannerId)
 	if err != nil {
-		return models.NewErrorService(http.StatusInternalServerError, err.Error())
+		return err
 	}
 
-	return models.ErrService{}
+	s.publish(models.NewBannerEvent(models.BannerEventDeleted, bannerId, banner))
+
+	return nil
 }
 
-func (s *BannersService) GetUserBanner(ctx context.Context, featureId int, tagId int, lastRevision bool) (models.Banner, models.ErrService) {
-	if tagId < 0 {
-		return models.Banner{}, models.NewErrorService(http.StatusBadRequest, "tag_id must be greater or equal to 0")
+// SubscribeChanges returns events about changes of banners matching filter until ctx is done,
+// then the channel is closed.
+func (s *BannersService) SubscribeChanges(ctx context.Context,
+	filter models.BannerEventFilter) (<-chan models.BannerEvent, error) {
+	if filter.FeatureId < 0 {
+		return nil, models.NewValidationError("feature_id must be greater or equal to 0")
+	}
+
+	for _, tagId := range filter.TagsIds {
+		if tagId < 0 {
+			return nil, models.NewValidationError("tag_id must be greater or equal to 0")
+		}
 	}
 
+	events, unsubscribe := s.events.Subscribe()
+
+	matched := make(chan models.BannerEvent)
+	go func() {
+		defer close(matched)
+		defer unsubscribe()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+
+				if !filter.Matches(event) {
+					continue
+				}
+
+				select {
+				case matched <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return matched, nil
+}
+
+// publish notifies subscribers about banner change.
+func (s *BannersService) publish(event models.BannerEvent) {
+	publishEvent(s.events, s.logger, event)
+}
+
+// GetUserBanner returns banner of the feature for the first tag in tagsIds which has one, so tags are given by priority.
+// If there is A/B experiment on feature and tag, one of its variants is chosen by user id and banner id
+// of the variant is returned, otherwise returned variant is 0.
+func (s *BannersService) GetUserBanner(ctx context.Context, featureId int, tagsIds []int, userId int,
+	lastRevision bool) (models.UserBanner, error) {
 	if featureId < 0 {
-		return models.Banner{}, models.NewErrorService(http.StatusBadRequest, "feature_id must be greater or equal to 0")
+		return models.UserBanner{}, models.NewValidationError("feature_id must be greater or equal to 0")
+	}
+
+	for _, tagId := range tagsIds {
+		if tagId < 0 {
+			return models.UserBanner{}, models.NewValidationError("tag_id must be greater or equal to 0")
+		}
+	}
+
+	for _, tagId := range tagsIds {
+		banner, err := s.getTagBanner(ctx, featureId, tagId, userId, lastRevision)
+		if err == nil {
+			return banner, nil
+		}
+
+		if !errors.Is(err, models.ErrNotFound) {
+			return models.UserBanner{}, err
+		}
+	}
+
+	return models.UserBanner{}, models.NewNotFoundError(fmt.Sprintf("banner with feature_id=%v not found for tags %v",
+		featureId, tagsIds))
+}
+
+func (s *BannersService) getTagBanner(ctx context.Context, featureId int, tagId int, userId int,
+	lastRevision bool) (models.UserBanner, error) {
+	variants, err := s.getExperimentVariants(ctx, featureId, tagId, lastRevision)
+	if err != nil {
+		return models.UserBanner{}, err
+	}
+
+	variant := 0
+	if len(variants) > 0 {
+		variant = models.PickVariant(variants, userId, featureId, tagId).BannerId
 	}
 
+	entry, err := s.getUserBanner(ctx, featureId, tagId, variant, lastRevision)
+	if err != nil {
+		return models.UserBanner{}, err
+	}
+
+	s.stats.RecordImpression(entry.BannerId)
+
+	return models.UserBanner{
+		FeatureId: featureId,
+		TagId:     tagId,
+		Variant:   variant,
+		Content:   entry.Banner,
+		ETag:      entry.ETag,
+		ExpiresAt: entry.ExpiresAt,
+	}, nil
+}
+
+// GetUserBanners returns banners of many features for the user. For every feature the banner is chosen like
+// in GetUserBanner, features without banner are absent from the result. Variants of experiments and banners
+// missing in cache are loaded from DB with one query per tag each, and cache is read with one batch request per tag.
+func (s *BannersService) GetUserBanners(ctx context.Context, featuresIds []int, tagsIds []int, userId int,
+	lastRevision bool) ([]models.UserBanner, error) {
+	if len(featuresIds) == 0 {
+		return nil, models.NewValidationError("feature_ids must not be empty")
+	}
+
+	if len(featuresIds) > maxUserBannersFeatures {
+		return nil, models.NewValidationError(fmt.Sprintf("at most %v feature_ids can be requested", maxUserBannersFeatures))
+	}
+
+	for _, featureId := range featuresIds {
+		if featureId < 0 {
+			return nil, models.NewValidationError("feature_id must be greater or equal to 0")
+		}
+	}
+
+	for _, tagId := range tagsIds {
+		if tagId < 0 {
+			return nil, models.NewValidationError("tag_id must be greater or equal to 0")
+		}
+	}
+
+	// repeated features are resolved once and returned in order of the first occurrence
+	unique := make([]int, 0, len(featuresIds))
+	for _, featureId := range featuresIds {
+		if !slices.Contains(unique, featureId) {
+			unique = append(unique, featureId)
+		}
+	}
+	featuresIds = unique
+
+	found := make(map[int]models.UserBanner, len(featuresIds))
+	pending := featuresIds
+	for _, tagId := range tagsIds {
+		if len(pending) == 0 {
+			break
+		}
+
+		banners, err := s.getTagBanners(ctx, pending, tagId, userId, lastRevision)
+		if err != nil {
+			return nil, err
+		}
+
+		rest := make([]int, 0, len(pending))
+		for _, featureId := range pending {
+			banner, ok := banners[featureId]
+			if !ok {
+				rest = append(rest, featureId)
+				continue
+			}
+
+			found[featureId] = banner
+		}
+		pending = rest
+	}
+
+	result := make([]models.UserBanner, 0, len(found))
+	for _, featureId := range featuresIds {
+		if banner, ok := found[featureId]; ok {
+			result = append(result, banner)
+		}
+	}
+
+	return result, nil
+}
+
+// getTagBanners returns banners of the tag for features which have one, keyed by feature id.
+func (s *BannersService) getTagBanners(ctx context.Context, featuresIds []int, tagId int, userId int,
+	lastRevision bool) (map[int]models.UserBanner, error) {
+	experiments, err := s.getFeaturesVariants(ctx, featuresIds, tagId, lastRevision)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]cache.Key, 0, len(featuresIds))
+	for _, featureId := range featuresIds {
+		variant := 0
+		if variants := experiments[featureId]; len(variants) > 0 {
+			variant = models.PickVariant(variants, userId, featureId, tagId).BannerId
+		}
+
+		keys = append(keys, cache.Key{TagId: tagId, FeatureId: featureId, Variant: variant})
+	}
+
+	entries := make(map[cache.Key]cache.Entry, len(keys))
+	if !lastRevision {
+		cached, err := s.cache.GetMany(keys)
+		if err != nil {
+			return nil, err
+		}
+
+		for key, entry := range cached {
+			if s.shouldRefreshEarly(entry.ExpiresAt) {
+				s.refreshUserBanner(ctx, key.FeatureId, key.TagId, key.Variant)
+			}
+
+			entries[key] = entry
+		}
+	}
+
+	missing := make([]cache.Key, 0, len(keys)-len(entries))
+	for _, key := range keys {
+		if _, ok := entries[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+
+	if len(missing) > 0 {
+		loaded, err := s.loadUserBanners(ctx, tagId, missing)
+		if err != nil {
+			return nil, err
+		}
+
+		for key, entry := range loaded {
+			entries[key] = entry
+		}
+
+		// like getUserBanner, banners not found in DB with lastRevision are served from cache if they are there
+		if lastRevision && len(loaded) < len(missing) {
+			notFound := make([]cache.Key, 0, len(missing)-len(loaded))
+			for _, key := range missing {
+				if _, ok := loaded[key]; !ok {
+					notFound = append(notFound, key)
+				}
+			}
+
+			cached, err := s.cache.GetMany(notFound)
+			if err != nil {
+				return nil, err
+			}
+
+			for key, entry := range cached {
+				entries[key] = entry
+			}
+		}
+	}
+
+	banners := make(map[int]models.UserBanner, len(entries))
+	for key, entry := range entries {
+		s.stats.RecordImpression(entry.BannerId)
+
+		banners[key.FeatureId] = models.UserBanner{
+			FeatureId: key.FeatureId,
+			TagId:     key.TagId,
+			Variant:   key.Variant,
+			Content:   entry.Banner,
+		}
+	}
+
+	return banners, nil
+}
+
+// loadUserBanners gets banners for keys of one tag from DB with one query and puts them into cache.
+func (s *BannersService) loadUserBanners(ctx context.Context, tagId int,
+	keys []cache.Key) (map[cache.Key]cache.Entry, error) {
+	featuresIds := make([]int, 0, len(keys))
+	bannersIds := make([]int, 0)
+	for _, key := range keys {
+		if key.Variant != 0 {
+			bannersIds = append(bannersIds, key.Variant)
+		} else {
+			featuresIds = append(featuresIds, key.FeatureId)
+		}
+	}
+
+	metrics.UserBannerLoads.Inc()
+
+	banners, err := s.repo.GetUserBanners(ctx, tagId, featuresIds, bannersIds)
+	if err != nil {
+		return nil, err
+	}
+
+	byFeature := make(map[int]models.AdminBanner, len(banners))
+	byId := make(map[int]models.AdminBanner, len(banners))
+	for _, banner := range banners {
+		if len(banner.Tags) > 0 {
+			byFeature[banner.Feature.ID] = banner
+		}
+		byId[banner.ID] = banner
+	}
+
+	entries := make(map[cache.Key]cache.Entry, len(keys))
+	for _, key := range keys {
+		banner, ok := byFeature[key.FeatureId]
+		if key.Variant != 0 {
+			banner, ok = byId[key.Variant]
+		}
+
+		if !ok {
+			continue
+		}
+
+		err = s.cache.Set(banner.Content, key.TagId, key.FeatureId, key.Variant, banner.ID, cacheExpiresAt(banner))
+		if err != nil {
+			return nil, err
+		}
+
+		entries[key] = s.newCacheEntry(banner)
+	}
+
+	return entries, nil
+}
+
+// getUserBanner returns cache entry of banner from cache or, on cache miss or if lastRevision is set, from DB.
+func (s *BannersService) getUserBanner(ctx context.Context, featureId int, tagId int, variant int,
+	lastRevision bool) (cache.Entry, error) {
 	if lastRevision {
-		banner, bannerId, err := s.repo.GetUserBanner(ctx, featureId, tagId)
+		banner, err := s.getBannerFromDB(ctx, featureId, tagId, variant)
 		if err != nil {
-			if strings.Contains(err.Error(), "not found") {
-				banner, err = s.cache.Get(tagId, featureId)
-				if err != nil {
-					if strings.Contains(err.Error(), "not found") {
-						return models.Banner{}, models.NewErrorService(http.StatusNotFound, err.Error())
+			if errors.Is(err, models.ErrNotFound) {
+				entry, cacheErr := s.cache.Get(tagId, featureId, variant)
+				if cacheErr != nil {
+					if errors.Is(cacheErr, cache.ErrNotFound) {
+						return cache.Entry{}, err
 					}
-					return models.Banner{}, models.NewErrorService(http.StatusInternalServerError, err.Error())
+					return cache.Entry{}, cacheErr
 				}
-				return banner, models.ErrService{}
+				return entry, nil
 			} else {
-				return models.Banner{}, models.NewErrorService(http.StatusInternalServerError, err.Error())
+				return cache.Entry{}, err
 			}
 		}
 
-		err = s.cache.Set(banner, tagId, featureId, bannerId)
+		err = s.cache.Set(banner.Content, tagId, featureId, variant, banner.ID, cacheExpiresAt(banner))
 		if err != nil {
-			return banner, models.NewErrorService(http.StatusInternalServerError, err.Error())
+			return cache.Entry{}, err
 		}
 
-		return banner, models.ErrService{}
+		return s.newCacheEntry(banner), nil
 	} else {
-		banner, err := s.cache.Get(tagId, featureId)
+		entry, err := s.cache.Get(tagId, featureId, variant)
 		if err != nil {
-			if err.Error() == "not found" {
-				banner, bannerId, err := s.repo.GetUserBanner(ctx, featureId, tagId)
-				if err != nil {
-					if strings.Contains(err.Error(), "not found") {
-						return models.Banner{}, models.NewErrorService(http.StatusNotFound, err.Error())
-					}
-					return models.Banner{}, models.NewErrorService(http.StatusInternalServerError, err.Error())
-				}
-
-				err = s.cache.Set(banner, tagId, featureId, bannerId)
+			if errors.Is(err, cache.ErrNotFound) {
+				banner, err := s.loadUserBanner(ctx, featureId, tagId, variant)
 				if err != nil {
-					return banner, models.NewErrorService(http.StatusInternalServerError, err.Error())
+					return cache.Entry{}, err
 				}
 
-				return banner, models.ErrService{}
+				return s.newCacheEntry(banner), nil
 			} else {
-				return models.Banner{}, models.NewErrorService(http.StatusInternalServerError, err.Error())
+				return cache.Entry{}, err
 			}
 		}
 
-		return banner, models.ErrService{}
+		if s.shouldRefreshEarly(entry.ExpiresAt) {
+			s.refreshUserBanner(ctx, featureId, tagId, variant)
+		}
+
+		return entry, nil
 	}
 }
 
-func (s *BannersService) GetAllBanners(ctx context.Context, featureId, tagId, limit, offset int) ([]models.AdminBanner, models.ErrService) {
-	if limit < 0 {
-		return nil, models.NewErrorService(http.StatusBadRequest, "limit must be greater than 0")
+// newCacheEntry returns entry of banner just loaded from DB and stored in cache, as it would be read from cache.
+func (s *BannersService) newCacheEntry(banner models.AdminBanner) cache.Entry {
+	expiresAt := time.Now().Add(s.cache.TTL())
+	if activeUntil := cacheExpiresAt(banner); !activeUntil.IsZero() && activeUntil.Before(expiresAt) {
+		expiresAt = activeUntil
 	}
 
-	if offset < 0 {
-		return nil, models.NewErrorService(http.StatusBadRequest, "offset must be greater or equal to 0")
+	return cache.Entry{
+		Banner:    banner.Content,
+		BannerId:  banner.ID,
+		ExpiresAt: expiresAt,
+		ETag:      models.BannerETag(banner.Content),
 	}
+}
 
-	if tagId < 0 {
-		return nil, models.NewErrorService(http.StatusBadRequest, "tag_id must be greater or equal to 0")
+// loadUserBanner gets user banner from DB and puts it into cache. Concurrent loads of the same banner are
+// coalesced, so only one query per tag_id:feature_id:variant key goes to DB at a time.
+func (s *BannersService) loadUserBanner(ctx context.Context, featureId int, tagId int,
+	variant int) (models.AdminBanner, error) {
+	banner, loaded, err := s.load(ctx, featureId, tagId, variant)
+	if !loaded {
+		metrics.UserBannerCoalesced.Inc()
 	}
 
-	if featureId < 0 {
-		return nil, models.NewErrorService(http.StatusBadRequest, "feature_id must be greater or equal to 0")
+	return banner, err
+}
+
+// refreshUserBanner reloads cached user banner in background before its cache entry expires.
+func (s *BannersService) refreshUserBanner(ctx context.Context, featureId int, tagId int, variant int) {
+	metrics.UserBannerEarlyRefreshes.Inc()
+
+	go s.load(ctx, featureId, tagId, variant)
+}
+
+// load runs coalesced load of user banner and reports whether this call made the query. The load is shared
+// by all waiting requests and may outlive the request which started it, so it is not canceled with ctx.
+func (s *BannersService) load(ctx context.Context, featureId int, tagId int,
+	variant int) (models.AdminBanner, bool, error) {
+	ctx = context.WithoutCancel(ctx)
+	loaded := false
+
+	res, err, _ := s.loads.Do(fmt.Sprintf("%v:%v:%v", tagId, featureId, variant), func() (interface{}, error) {
+		loaded = true
+		metrics.UserBannerLoads.Inc()
+
+		start := time.Now()
+
+		banner, err := s.getBannerFromDB(ctx, featureId, tagId, variant)
+		if err != nil {
+			return nil, err
+		}
+
+		s.lastLoadDuration.Store(int64(time.Since(start)))
+
+		return banner, s.cache.Set(banner.Content, tagId, featureId, variant, banner.ID, cacheExpiresAt(banner))
+	})
+
+	banner, _ := res.(models.AdminBanner)
+
+	return banner, loaded, err
+}
+
+// getBannerFromDB returns banner of the variant or, if there is no experiment, the banner of feature and tag.
+func (s *BannersService) getBannerFromDB(ctx context.Context, featureId int, tagId int,
+	variant int) (models.AdminBanner, error) {
+	if variant != 0 {
+		return s.repo.GetLiveBanner(ctx, variant)
+	}
+
+	return s.repo.GetUserBanner(ctx, featureId, tagId)
+}
+
+// getFeaturesVariants returns variants of experiments on the tag for many features, keyed by feature id.
+// Variants are taken from memory like in getExperimentVariants, missing ones are loaded with one query.
+func (s *BannersService) getFeaturesVariants(ctx context.Context, featuresIds []int, tagId int,
+	fresh bool) (map[int][]models.Variant, error) {
+	experiments := make(map[int][]models.Variant, len(featuresIds))
+	missing := featuresIds
+
+	if !fresh {
+		missing = make([]int, 0, len(featuresIds))
+
+		s.variantsMu.Lock()
+		for _, featureId := range featuresIds {
+			loaded, ok := s.variants[fmt.Sprintf("%v:%v", tagId, featureId)]
+			if ok && time.Since(loaded.loadedAt) < s.variantsCacheTTL {
+				experiments[featureId] = loaded.variants
+				continue
+			}
+
+			missing = append(missing, featureId)
+		}
+		s.variantsMu.Unlock()
 	}
 
-	banners, err := s.repo.GetAllBanners(ctx, featureId, tagId, limit, offset)
+	if len(missing) == 0 {
+		return experiments, nil
+	}
+
+	variants, err := s.variantsRepo.GetFeaturesVariants(ctx, tagId, missing)
 	if err != nil {
-		return nil, models.NewErrorService(http.StatusInternalServerError, err.Error())
+		return nil, err
+	}
+
+	s.variantsMu.Lock()
+	for k, loaded := range s.variants {
+		if time.Since(loaded.loadedAt) >= s.variantsCacheTTL {
+			delete(s.variants, k)
+		}
+	}
+	// features without experiment are kept too, so they are not queried again until TTL
+	for _, featureId := range missing {
+		experiments[featureId] = variants[featureId]
+		s.variants[fmt.Sprintf("%v:%v", tagId, featureId)] = loadedVariants{
+			variants: variants[featureId],
+			loadedAt: time.Now(),
+		}
 	}
+	s.variantsMu.Unlock()
 
-	return banners, models.ErrService{}
+	return experiments, nil
+}
+
+// getExperimentVariants returns variants of experiment with banners which can be shown to users marked live.
+// Variants are kept in memory for variantsCacheTTL, fresh ones are loaded from DB if they are too old or fresh is true.
+func (s *BannersService) getExperimentVariants(ctx context.Context, featureId int, tagId int,
+	fresh bool) ([]models.Variant, error) {
+	key := fmt.Sprintf("%v:%v", tagId, featureId)
+
+	if !fresh {
+		s.variantsMu.Lock()
+		loaded, ok := s.variants[key]
+		s.variantsMu.Unlock()
+
+		if ok && time.Since(loaded.loadedAt) < s.variantsCacheTTL {
+			return loaded.variants, nil
+		}
+	}
+
+	variants, err := s.variantsRepo.GetVariants(ctx, featureId, tagId)
+	if err != nil {
+		return nil, err
+	}
+
+	s.variantsMu.Lock()
+	for k, loaded := range s.variants {
+		if time.Since(loaded.loadedAt) >= s.variantsCacheTTL {
+			delete(s.variants, k)
+		}
+	}
+	s.variants[key] = loadedVariants{variants: variants, loadedAt: time.Now()}
+	s.variantsMu.Unlock()
+
+	return variants, nil
+}
+
+func (s *BannersService) forgetVariants(featureId int, tagId int) {
+	s.variantsMu.Lock()
+	delete(s.variants, fmt.Sprintf("%v:%v", tagId, featureId))
+	s.variantsMu.Unlock()
+}
+
+// SetVariants starts A/B experiment on feature and tag or replaces variants of the running one.
+// Other instances of the service pick up the change after their in-memory variants expire.
+func (s *BannersService) SetVariants(ctx context.Context, featureId int, tagId int, variants []models.Variant) error {
+	if featureId <= 0 {
+		return models.NewValidationError("feature_id must be greater than 0")
+	}
+
+	if tagId <= 0 {
+		return models.NewValidationError("tag_id must be greater than 0")
+	}
+
+	err := models.ValidateVariants(variants)
+	if err != nil {
+		return models.NewValidationError(err.Error())
+	}
+
+	for _, v := range variants {
+		banner, err := s.repo.GetBannerByID(ctx, v.BannerId)
+		if err != nil {
+			if errors.Is(err, models.ErrNotFound) {
+				return models.NewValidationError(fmt.Sprintf("banner_id=%v of variant not found", v.BannerId))
+			}
+			return err
+		}
+
+		err = models.ValidateVariantBanner(banner, featureId, tagId)
+		if err != nil {
+			return models.NewValidationError(err.Error())
+		}
+	}
+
+	err = s.variantsRepo.Set(ctx, featureId, tagId, variants)
+	if err != nil {
+		return err
+	}
+
+	s.forgetVariants(featureId, tagId)
+	s.publish(models.NewFeatureTagEvent(models.BannerEventUpdated, featureId, tagId))
+
+	return nil
+}
+
+func (s *BannersService) GetVariants(ctx context.Context, featureId int, tagId int) ([]models.Variant, error) {
+	if featureId <= 0 {
+		return nil, models.NewValidationError("feature_id must be greater than 0")
+	}
+
+	if tagId <= 0 {
+		return nil, models.NewValidationError("tag_id must be greater than 0")
+	}
+
+	variants, err := s.variantsRepo.GetVariants(ctx, featureId, tagId)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(variants) == 0 {
+		return nil, models.NewNotFoundError(fmt.Sprintf("experiment with feature_id=%v and tag_id=%v not found", featureId, tagId))
+	}
+
+	return variants, nil
+}
+
+func (s *BannersService) DeleteVariants(ctx context.Context, featureId int, tagId int) error {
+	if featureId <= 0 {
+		return models.NewValidationError("feature_id must be greater than 0")
+	}
+
+	if tagId <= 0 {
+		return models.NewValidationError("tag_id must be greater than 0")
+	}
+
+	err := s.variantsRepo.Delete(ctx, featureId, tagId)
+	if err != nil {
+		return err
+	}
+
+	s.forgetVariants(featureId, tagId)
+	s.publish(models.NewFeatureTagEvent(models.BannerEventUpdated, featureId, tagId))
+
+	return nil
+}
+
+// cacheExpiresAt returns the end of banner activation window, so cached banner doesn't outlive it.
+// Zero time means that the window is not limited and the configured cache TTL is used.
+func cacheExpiresAt(banner models.AdminBanner) time.Time {
+	if banner.ActiveUntil == nil {
+		return time.Time{}
+	}
+
+	return *banner.ActiveUntil
+}
+
+// shouldRefreshEarly decides whether cached banner should be reloaded before it expires.
+// The probability grows as expiration comes closer and as loads from DB get slower (XFetch algorithm).
+func (s *BannersService) shouldRefreshEarly(expiresAt time.Time) bool {
+	if s.earlyRefreshBeta <= 0 {
+		return false
+	}
+
+	delta := float64(s.lastLoadDuration.Load())
+	gap := time.Duration(-delta * s.earlyRefreshBeta * math.Log(1-rand.Float64()))
+
+	return !time.Now().Add(gap).Before(expiresAt)
+}
+
+func (s *BannersService) GetAllBanners(ctx context.Context, filter models.BannerFilter,
+	page models.PageRequest) ([]models.AdminBanner, models.PageInfo, error) {
+	cursor, limit, err := s.pager.parse(page)
+	if err != nil {
+		return nil, models.PageInfo{}, err
+	}
+
+	err = filter.Validate()
+	if err != nil {
+		return nil, models.PageInfo{}, models.NewValidationError(err.Error())
+	}
+
+	err = filter.CheckCursor(cursor)
+	if err != nil {
+		return nil, models.PageInfo{}, models.NewValidationError(err.Error())
+	}
+
+	banners, total, err := s.repo.GetAllBanners(ctx, filter, cursor, limit+1)
+	if err != nil {
+		return nil, models.PageInfo{}, err
+	}
+
+	banners, next := cutPage(banners, limit, filter.Cursor)
+
+	return banners, models.PageInfo{NextCursor: next, Total: total}, nil
+}
+
+// RunScheduler evicts cached banners whose activation window starts or ends and notifies subscribers about them,
+// until ctx is done.
+// Cached entries already expire at the end of the window, eviction also covers banners cached before the window was changed.
+func (s *BannersService) RunScheduler(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(s.schedulerInterval)
+		defer ticker.Stop()
+
+		last := time.Now()
+		for {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+
+			now := time.Now()
+
+			ids, err := s.repo.GetWindowBoundaryBanners(ctx, last, now)
+			if err != nil {
+				s.logger.Error().Err(err).Time("from", last).Time("to", now).
+					Msg("error occurred while getting banners with activation window boundary")
+				continue
+			}
+
+			for _, id := range ids {
+				if err := s.cache.Delete(id); err != nil {
+					s.logger.Error().Err(err).Int("banner_id", id).
+						Msg("error occurred while deleting banner with activation window boundary from cache")
+				}
+
+				banner, err := s.repo.GetBannerByID(ctx, id)
+				if err != nil {
+					s.logger.Error().Err(err).Int("banner_id", id).
+						Msg("error occurred while getting banner to publish toggled event")
+					continue
+				}
+
+				s.publish(models.NewBannerEvent(models.BannerEventToggled, id, banner))
+			}
+
+			last = now
+		}
+	}()
 }
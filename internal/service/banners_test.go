@@ -0,0 +1,208 @@
+package service
+
+import (
+	"avito-test2024-spring/internal/config"
+	"avito-test2024-spring/internal/models"
+	"avito-test2024-spring/internal/repository"
+	"avito-test2024-spring/pkg/cache"
+	"avito-test2024-spring/pkg/pubsub"
+	"context"
+	"errors"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+// schedulerBannersRepo returns boundary banners of the scheduler, other methods of the repo are not used.
+type schedulerBannersRepo struct {
+	repository.Banners
+
+	mu sync.Mutex
+	// ranges passed to GetWindowBoundaryBanners
+	ranges [][2]time.Time
+	// results of GetWindowBoundaryBanners calls, the last one is repeated
+	results []error
+	ids     []int
+}
+
+func (r *schedulerBannersRepo) GetWindowBoundaryBanners(ctx context.Context, from time.Time,
+	to time.Time) ([]int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	call := len(r.ranges)
+	r.ranges = append(r.ranges, [2]time.Time{from, to})
+
+	if err := r.results[min(call, len(r.results)-1)]; err != nil {
+		return nil, err
+	}
+
+	// boundary banners are returned once, like the query returns them only for the range with the boundary
+	ids := r.ids
+	r.ids = nil
+
+	return ids, nil
+}
+
+func (r *schedulerBannersRepo) GetBannerByID(ctx context.Context, bannerId int) (models.AdminBanner, error) {
+	return models.AdminBanner{
+		ID:      bannerId,
+		Feature: models.Feature{ID: 2},
+		Tags:    []models.Tag{{ID: 3}},
+	}, nil
+}
+
+func (r *schedulerBannersRepo) getRanges() [][2]time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([][2]time.Time(nil), r.ranges...)
+}
+
+func TestBannersService_RunScheduler(t *testing.T) {
+	repo := &schedulerBannersRepo{
+		// the first run fails, so its range is checked again by the next run
+		results: []error{errors.New("connection refused"), nil},
+		ids:     []int{1},
+	}
+	memoryCache := cache.NewMemoryCache(10, time.Minute)
+	events := pubsub.NewHub(1)
+
+	s := NewBannersService(repo, nil, nil, nil, memoryCache, events, zerolog.Nop(),
+		config.BannersConfig{SchedulerInterval: 10 * time.Millisecond}, config.PaginationConfig{})
+
+	require.NoError(t, memoryCache.Set(models.Banner(`{"title": "banner"}`), 3, 2, 0, 1, time.Time{}))
+
+	subscription, unsubscribe := events.Subscribe()
+	defer unsubscribe()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.RunScheduler(ctx)
+
+	select {
+	case event := <-subscription:
+		require.Equal(t, models.BannerEventToggled, event.Type)
+		require.Equal(t, 1, event.BannerId)
+		require.Equal(t, []int{2}, event.FeaturesIds)
+		require.Equal(t, []int{3}, event.TagsIds)
+	case <-time.After(time.Second):
+		t.Fatal("scheduler didn't notify about banner with window boundary")
+	}
+
+	_, err := memoryCache.Get(3, 2, 0)
+	require.ErrorIs(t, err, cache.ErrNotFound)
+
+	require.Eventually(t, func() bool { return len(repo.getRanges()) >= 3 }, time.Second, 5*time.Millisecond)
+	cancel()
+
+	ranges := repo.getRanges()
+	require.Equal(t, ranges[0][0], ranges[1][0], "range of failed run must be checked again")
+	for i := 2; i < len(ranges); i++ {
+		require.Equal(t, ranges[i-1][1], ranges[i][0], "ranges of runs must be adjacent")
+	}
+}
+
+// userBannersRepo returns banners of one tag for the batch of features.
+type userBannersRepo struct {
+	repository.Banners
+
+	banners []models.AdminBanner
+}
+
+func (r *userBannersRepo) GetUserBanners(ctx context.Context, tagId int, featuresIds []int,
+	bannersIds []int) ([]models.AdminBanner, error) {
+	return r.banners, nil
+}
+
+// featuresVariantsRepo counts batched reads of variants, reading variants of one feature panics.
+type featuresVariantsRepo struct {
+	repository.Variants
+
+	calls    int
+	variants map[int][]models.Variant
+}
+
+func (r *featuresVariantsRepo) GetFeaturesVariants(ctx context.Context, tagId int,
+	featuresIds []int) (map[int][]models.Variant, error) {
+	r.calls++
+
+	return r.variants, nil
+}
+
+type noopStats struct {
+	Stats
+}
+
+func (noopStats) RecordImpression(bannerId int) {}
+
+func TestBannersService_GetUserBanners(t *testing.T) {
+	repo := &userBannersRepo{banners: []models.AdminBanner{
+		{ID: 10, Feature: models.Feature{ID: 1}, Tags: []models.Tag{{ID: 5}}, Content: models.Banner(`{"b": 10}`)},
+		{ID: 20, Feature: models.Feature{ID: 2}, Content: models.Banner(`{"b": 20}`)},
+	}}
+	variantsRepo := &featuresVariantsRepo{variants: map[int][]models.Variant{
+		2: {{BannerId: 20, Weight: 1, Live: true}},
+	}}
+	memoryCache := cache.NewMemoryCache(10, time.Minute)
+
+	s := NewBannersService(repo, nil, variantsRepo, noopStats{}, memoryCache, pubsub.NewHub(1),
+		zerolog.Nop(), config.BannersConfig{VariantsCacheTTL: time.Minute}, config.PaginationConfig{})
+
+	// banner of feature 3 is not in DB anymore, but it is still cached
+	require.NoError(t, memoryCache.Set(models.Banner(`{"b": 30}`), 5, 3, 0, 30, time.Time{}))
+
+	for _, lastRevision := range []bool{true, false} {
+		banners, err := s.GetUserBanners(context.Background(), []int{1, 2, 3, 4}, []int{5}, 1, lastRevision)
+		require.NoError(t, err)
+		require.Equal(t, []models.UserBanner{
+			{FeatureId: 1, TagId: 5, Content: models.Banner(`{"b": 10}`)},
+			{FeatureId: 2, TagId: 5, Variant: 20, Content: models.Banner(`{"b": 20}`)},
+			{FeatureId: 3, TagId: 5, Content: models.Banner(`{"b": 30}`)},
+		}, banners)
+	}
+
+	// variants are read with one query for all features, the second request takes them from memory
+	require.Equal(t, 1, variantsRepo.calls)
+}
+
+func TestBannersService_SubscribeChanges(t *testing.T) {
+	events := pubsub.NewHub(10)
+	s := NewBannersService(nil, nil, nil, nil, nil, events, zerolog.Nop(), config.BannersConfig{},
+		config.PaginationConfig{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes, err := s.SubscribeChanges(ctx, models.BannerEventFilter{FeatureId: 1, TagsIds: []int{2, 3}})
+	require.NoError(t, err)
+
+	otherFeature := models.BannerEvent{Type: models.BannerEventUpdated, BannerId: 1, FeaturesIds: []int{5},
+		TagsIds: []int{2}}
+	otherTags := models.BannerEvent{Type: models.BannerEventUpdated, BannerId: 2, FeaturesIds: []int{1},
+		TagsIds: []int{4}}
+	matched := models.BannerEvent{Type: models.BannerEventDeleted, BannerId: 3, FeaturesIds: []int{1},
+		TagsIds: []int{3, 4}}
+
+	require.NoError(t, events.Publish(otherFeature))
+	require.NoError(t, events.Publish(otherTags))
+	require.NoError(t, events.Publish(matched))
+
+	select {
+	case event := <-changes:
+		require.Equal(t, matched, event)
+	case <-time.After(time.Second):
+		t.Fatal("matched event is not received")
+	}
+
+	cancel()
+
+	select {
+	case _, ok := <-changes:
+		require.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("channel is not closed after ctx is done")
+	}
+}
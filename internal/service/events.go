@@ -0,0 +1,16 @@
+package service
+
+import (
+	"avito-test2024-spring/internal/models"
+	"avito-test2024-spring/pkg/pubsub"
+	"github.com/rs/zerolog"
+)
+
+// publishEvent notifies subscribers about banner change. Events are best effort: the change is already saved
+// and clients get it from cache after TTL anyway, so publish errors don't fail the request and are only logged.
+func publishEvent(events pubsub.PubSub, logger zerolog.Logger, event models.BannerEvent) {
+	if err := events.Publish(event); err != nil {
+		logger.Error().Err(err).Str("type", event.Type).Int("banner_id", event.BannerId).
+			Msg("error occurred while publishing banner event")
+	}
+}
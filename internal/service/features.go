@@ -1,62 +1,145 @@
 package service
 
 import (
+	"avito-test2024-spring/internal/config"
 	"avito-test2024-spring/internal/models"
 	"avito-test2024-spring/internal/repository"
+	"avito-test2024-spring/pkg/cache"
+	"bytes"
 	"context"
-	"net/http"
+	"encoding/json"
 	"strings"
 )
 
 type FeaturesService struct {
-	repo repository.Features
+	repo  repository.Features
+	cache cache.Cache
+	pager pager
 }
 
-func NewFeaturesService(repo repository.Features) *FeaturesService {
+func NewFeaturesService(repo repository.Features, cache cache.Cache, pagination config.PaginationConfig) *FeaturesService {
 	return &FeaturesService{
-		repo: repo,
+		repo:  repo,
+		cache: cache,
+		pager: newPager(pagination),
 	}
 }
 
-func (s *FeaturesService) AddFeature(ctx context.Context) (int, models.ErrService) {
-	featureId, err := s.repo.Create(ctx)
+func (s *FeaturesService) AddFeature(ctx context.Context, metadata models.Metadata) (int, error) {
+	err := metadata.Normalize()
 	if err != nil {
-		return -1, models.NewErrorService(http.StatusInternalServerError, err.Error())
+		return -1, models.NewValidationError(err.Error())
 	}
 
-	return featureId, models.ErrService{}
+	featureId, err := s.repo.Create(ctx, metadata)
+	if err != nil {
+		return -1, err
+	}
+
+	return featureId, nil
+}
+
+// UpdateFeature changes metadata of the feature, fields missing in the update are kept.
+func (s *FeaturesService) UpdateFeature(ctx context.Context, featureId int, update models.MetadataUpdate) error {
+	feature, err := s.GetFeature(ctx, featureId)
+	if err != nil {
+		return err
+	}
+
+	update.Apply(&feature.Metadata)
+
+	err = feature.Normalize()
+	if err != nil {
+		return models.NewValidationError(err.Error())
+	}
+
+	return s.repo.Update(ctx, feature)
 }
 
-func (s *FeaturesService) DeleteFeature(ctx context.Context, featureId int) models.ErrService {
+func (s *FeaturesService) GetFeature(ctx context.Context, featureId int) (models.Feature, error) {
 	if featureId <= 0 {
-		return models.NewErrorService(http.StatusBadRequest, "feature_id must be greater than 0")
+		return models.Feature{}, models.NewValidationError("feature_id must be greater than 0")
+	}
+
+	return s.repo.GetFeatureById(ctx, featureId)
+}
+
+func (s *FeaturesService) GetFeatureByName(ctx context.Context, name string) (models.Feature, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return models.Feature{}, models.NewValidationError("name must not be empty")
+	}
+
+	return s.repo.GetFeatureByName(ctx, name)
+}
+
+func (s *FeaturesService) DeleteFeature(ctx context.Context, featureId int) error {
+	if featureId <= 0 {
+		return models.NewValidationError("feature_id must be greater than 0")
 	}
 
 	err := s.repo.Delete(ctx, featureId)
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			return models.NewErrorService(http.StatusNotFound, err.Error())
-		}
+		return err
+	}
 
-		return models.NewErrorService(http.StatusInternalServerError, err.Error())
+	err = s.cache.DeleteByFeature(featureId)
+	if err != nil {
+		return err
 	}
 
-	return models.ErrService{}
+	return nil
 }
 
-func (s *FeaturesService) GetAllFeatures(ctx context.Context, limit int, offset int) ([]models.Feature, models.ErrService) {
-	if limit < 0 {
-		return nil, models.NewErrorService(http.StatusBadRequest, "limit must be greater than 0")
+func (s *FeaturesService) GetAllFeatures(ctx context.Context, page models.PageRequest) ([]models.Feature, models.PageInfo, error) {
+	cursor, limit, err := s.pager.parse(page)
+	if err != nil {
+		return nil, models.PageInfo{}, err
+	}
+
+	features, total, err := s.repo.GetAllFeatures(ctx, cursor.AfterId, limit+1)
+	if err != nil {
+		return nil, models.PageInfo{}, err
+	}
+
+	features, next := cutPage(features, limit, func(f models.Feature) models.Cursor { return idCursor(f.ID) })
+
+	return features, models.PageInfo{NextCursor: next, Total: total}, nil
+}
+
+func (s *FeaturesService) SetContentSchema(ctx context.Context, featureId int, schema json.RawMessage) error {
+	if featureId <= 0 {
+		return models.NewValidationError("feature_id must be greater than 0")
+	}
+
+	schema = bytes.TrimSpace(schema)
+	if string(schema) == "null" {
+		schema = nil
+	}
+
+	if len(schema) != 0 {
+		if err := models.ValidateContentSchema(schema); err != nil {
+			return models.NewValidationError(err.Error())
+		}
 	}
 
-	if offset < 0 {
-		return nil, models.NewErrorService(http.StatusBadRequest, "offset must be greater or equal to 0")
+	err := s.repo.SetContentSchema(ctx, featureId, schema)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *FeaturesService) GetContentSchema(ctx context.Context, featureId int) (json.RawMessage, error) {
+	if featureId <= 0 {
+		return nil, models.NewValidationError("feature_id must be greater than 0")
 	}
 
-	features, err := s.repo.GetAllFeatures(ctx, limit, offset)
+	schema, err := s.repo.GetContentSchema(ctx, featureId)
 	if err != nil {
-		return nil, models.NewErrorService(http.StatusInternalServerError, err.Error())
+		return nil, err
 	}
 
-	return features, models.ErrService{}
+	return schema, nil
 }
@@ -0,0 +1,212 @@
+package service
+
+import (
+	"avito-test2024-spring/internal/config"
+	"avito-test2024-spring/internal/models"
+	"avito-test2024-spring/internal/repository"
+	"avito-test2024-spring/pkg/cache"
+	"avito-test2024-spring/pkg/pubsub"
+	"context"
+	"errors"
+	"github.com/rs/zerolog"
+	"time"
+)
+
+type JobsService struct {
+	repo        repository.Jobs
+	bannersRepo repository.Banners
+	cache       cache.Cache
+	events      pubsub.PubSub
+	logger      zerolog.Logger
+
+	queue        chan int
+	workers      int
+	batchSize    int
+	pollInterval time.Duration
+	leaseTimeout time.Duration
+}
+
+func NewJobsService(repo repository.Jobs, bannersRepo repository.Banners, cache cache.Cache, events pubsub.PubSub,
+	logger zerolog.Logger, cfg config.JobsConfig) *JobsService {
+	return &JobsService{
+		repo:         repo,
+		bannersRepo:  bannersRepo,
+		cache:        cache,
+		events:       events,
+		logger:       logger,
+		queue:        make(chan int, cfg.QueueSize),
+		workers:      cfg.Workers,
+		batchSize:    cfg.BatchSize,
+		pollInterval: cfg.PollInterval,
+		leaseTimeout: cfg.LeaseTimeout,
+	}
+}
+
+func (s *JobsService) AddBannersDeleteJob(ctx context.Context, featureId int, tagId int) (int, error) {
+	if tagId < 0 {
+		return -1, models.NewValidationError("tag_id must be greater or equal to 0")
+	}
+
+	if featureId < 0 {
+		return -1, models.NewValidationError("feature_id must be greater or equal to 0")
+	}
+
+	if featureId == 0 && tagId == 0 {
+		return -1, models.NewValidationError("feature_id or tag_id must be set")
+	}
+
+	job := models.Job{
+		FeatureId: featureId,
+		TagId:     tagId,
+		Status:    models.JobStatusPending,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	jobId, err := s.repo.Create(ctx, job)
+	if err != nil {
+		return -1, err
+	}
+
+	// if queue is full, job stays pending and will be picked up by the next poll
+	select {
+	case s.queue <- jobId:
+	default:
+	}
+
+	return jobId, nil
+}
+
+func (s *JobsService) GetJob(ctx context.Context, jobId int) (models.Job, error) {
+	if jobId <= 0 {
+		return models.Job{}, models.NewValidationError("job id must be greater than 0")
+	}
+
+	job, err := s.repo.GetJobById(ctx, jobId)
+	if err != nil {
+		return models.Job{}, err
+	}
+
+	return job, nil
+}
+
+// Run starts background workers which process queued jobs until ctx is done.
+// Pending jobs are also polled from the DB, so jobs left after queue overflow are not lost. Running jobs
+// which were not updated for the lease timeout are polled too, so jobs of crashed or restarted workers are resumed.
+func (s *JobsService) Run(ctx context.Context) {
+	for i := 0; i < s.workers; i++ {
+		go s.work(ctx)
+	}
+
+	go s.poll(ctx)
+}
+
+func (s *JobsService) poll(ctx context.Context) {
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		jobs, err := s.repo.GetPendingJobs(ctx, s.staleBefore())
+		if err != nil {
+			s.logger.Error().Err(err).Msg("error occurred while polling pending jobs")
+		}
+
+		for _, jobId := range jobs {
+			select {
+			case s.queue <- jobId:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *JobsService) work(ctx context.Context) {
+	for {
+		select {
+		case jobId := <-s.queue:
+			if err := s.processBannersDelete(ctx, jobId); err != nil {
+				s.logger.Error().Err(err).Int("job_id", jobId).Msg("error occurred while processing banners delete job")
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// processBannersDelete deletes banners of the job by batches. updated_at of the job is its lease: it is renewed
+// after every batch, and if the worker stops, the job is taken again after the lease timeout. Batches are
+// deleted by feature and tag, so the job is resumed from banners which are left.
+func (s *JobsService) processBannersDelete(ctx context.Context, jobId int) error {
+	job, err := s.repo.Start(ctx, jobId, s.staleBefore())
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			// job is already processed by another worker
+			return nil
+		}
+		return err
+	}
+
+	remaining, err := s.bannersRepo.CountByFeatureTag(ctx, job.FeatureId, job.TagId)
+	if err != nil {
+		return s.failJob(ctx, job, err)
+	}
+	job.Total = job.Processed + remaining
+
+	for {
+		banners, err := s.bannersRepo.DeleteByFeatureTag(ctx, job.FeatureId, job.TagId, s.batchSize)
+		if err != nil {
+			return s.failJob(ctx, job, err)
+		}
+
+		for _, banner := range banners {
+			if err := s.cache.Delete(banner.ID); err != nil {
+				return s.failJob(ctx, job, err)
+			}
+		}
+
+		if len(banners) > 0 {
+			publishEvent(s.events, s.logger, models.NewBannersEvent(models.BannerEventDeleted, banners))
+		}
+
+		job.Processed += len(banners)
+		job.UpdatedAt = time.Now()
+
+		if len(banners) < s.batchSize {
+			break
+		}
+
+		// job stays running and is resumed after the lease timeout
+		if err := s.repo.Update(ctx, job); err != nil {
+			return err
+		}
+	}
+
+	job.Status = models.JobStatusDone
+
+	return s.repo.Update(ctx, job)
+}
+
+// failJob saves the error in the job and returns it, joined with the error of saving if it fails.
+func (s *JobsService) failJob(ctx context.Context, job models.Job, err error) error {
+	job.Status = models.JobStatusFailed
+	job.Error = err.Error()
+	job.UpdatedAt = time.Now()
+
+	if updateErr := s.repo.Update(ctx, job); updateErr != nil {
+		return errors.Join(err, updateErr)
+	}
+
+	return err
+}
+
+// staleBefore returns the time running jobs which were updated earlier are considered abandoned.
+func (s *JobsService) staleBefore() time.Time {
+	return time.Now().Add(-s.leaseTimeout)
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"avito-test2024-spring/internal/config"
+	"avito-test2024-spring/internal/models"
+)
+
+// pager converts page requests of admin lists into keyset conditions for repositories.
+type pager struct {
+	defaultLimit int
+	maxLimit     int
+}
+
+func newPager(cfg config.PaginationConfig) pager {
+	return pager{
+		defaultLimit: cfg.DefaultLimit,
+		maxLimit:     cfg.MaxLimit,
+	}
+}
+
+// parse returns cursor after which the page starts and its size.
+func (p pager) parse(page models.PageRequest) (models.Cursor, int, error) {
+	if page.Limit < 0 {
+		return models.Cursor{}, 0, models.NewValidationError("limit must be greater than 0")
+	}
+
+	limit := page.Limit
+	if limit == 0 {
+		limit = p.defaultLimit
+	}
+
+	if limit > p.maxLimit {
+		limit = p.maxLimit
+	}
+
+	cursor, err := models.DecodeCursor(page.Cursor)
+	if err != nil {
+		return models.Cursor{}, 0, models.NewValidationError(err.Error())
+	}
+
+	return cursor, limit, nil
+}
+
+// cutPage trims items loaded with limit+1 to the page size and makes cursor of the next page if there is one.
+func cutPage[T any](items []T, limit int, cursor func(T) models.Cursor) ([]T, string) {
+	if len(items) <= limit {
+		return items, ""
+	}
+
+	items = items[:limit]
+
+	return items, models.EncodeCursor(cursor(items[limit-1]))
+}
+
+// idCursor is the cursor of lists sorted by id.
+func idCursor(id int) models.Cursor {
+	return models.Cursor{AfterId: id}
+}
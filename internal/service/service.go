@@ -1,39 +1,78 @@
 package service
 
 import (
+	"avito-test2024-spring/internal/config"
 	"avito-test2024-spring/internal/models"
 	"avito-test2024-spring/internal/repository"
 	"avito-test2024-spring/pkg/auth"
 	"avito-test2024-spring/pkg/cache"
+	"avito-test2024-spring/pkg/pubsub"
 	"context"
+	"encoding/json"
+	"github.com/rs/zerolog"
+	"time"
 )
 
 type Banners interface {
-	AddBanner(ctx context.Context, input BannerAddInput) (int, models.ErrService)
-	UpdateBanner(ctx context.Context) models.ErrService
-	DeleteBanner(ctx context.Context, bannerId int) models.ErrService
-	GetUserBanner(ctx context.Context, featureId int, tagId int, lastRevision bool) (models.Banner, models.ErrService)
-	GetAllBanners(ctx context.Context, featureId, tagId, limit, offset int) ([]models.AdminBanner, models.ErrService)
+	AddBanner(ctx context.Context, input BannerAddInput) (int, error)
+	UpdateBanner(ctx context.Context) error
+	DeleteBanner(ctx context.Context, bannerId int) error
+	GetUserBanner(ctx context.Context, featureId int, tagsIds []int, userId int, lastRevision bool) (models.UserBanner, error)
+	GetUserBanners(ctx context.Context, featuresIds []int, tagsIds []int, userId int, lastRevision bool) ([]models.UserBanner, error)
+	GetAllBanners(ctx context.Context, filter models.BannerFilter, page models.PageRequest) ([]models.AdminBanner, models.PageInfo, error)
+	GetBannerVersions(ctx context.Context, bannerId int) ([]models.BannerVersion, error)
+	ActivateBannerVersion(ctx context.Context, bannerId int, version int) error
+	RunScheduler(ctx context.Context)
+	SetVariants(ctx context.Context, featureId int, tagId int, variants []models.Variant) error
+	GetVariants(ctx context.Context, featureId int, tagId int) ([]models.Variant, error)
+	DeleteVariants(ctx context.Context, featureId int, tagId int) error
+	SubscribeChanges(ctx context.Context, filter models.BannerEventFilter) (<-chan models.BannerEvent, error)
 }
 
 type Tags interface {
-	AddTag(ctx context.Context) (int, models.ErrService)
-	DeleteTag(ctx context.Context, tagId int) models.ErrService
-	GetAllTags(ctx context.Context, limit int, offset int) ([]models.Tag, models.ErrService)
+	AddTag(ctx context.Context, metadata models.Metadata) (int, error)
+	UpdateTag(ctx context.Context, tagId int, update models.MetadataUpdate) error
+	GetTag(ctx context.Context, tagId int) (models.Tag, error)
+	GetTagByName(ctx context.Context, name string) (models.Tag, error)
+	DeleteTag(ctx context.Context, tagId int) error
+	GetAllTags(ctx context.Context, page models.PageRequest) ([]models.Tag, models.PageInfo, error)
 }
 
 type Features interface {
-	AddFeature(ctx context.Context) (int, models.ErrService)
-	DeleteFeature(ctx context.Context, featureId int) models.ErrService
-	GetAllFeatures(ctx context.Context, limit int, offset int) ([]models.Feature, models.ErrService)
+	AddFeature(ctx context.Context, metadata models.Metadata) (int, error)
+	UpdateFeature(ctx context.Context, featureId int, update models.MetadataUpdate) error
+	GetFeature(ctx context.Context, featureId int) (models.Feature, error)
+	GetFeatureByName(ctx context.Context, name string) (models.Feature, error)
+	DeleteFeature(ctx context.Context, featureId int) error
+	GetAllFeatures(ctx context.Context, page models.PageRequest) ([]models.Feature, models.PageInfo, error)
+	SetContentSchema(ctx context.Context, featureId int, schema json.RawMessage) error
+	GetContentSchema(ctx context.Context, featureId int) (json.RawMessage, error)
 }
 
 type Users interface {
-	AddUser(ctx context.Context, input UserAddInput) (string, models.ErrService)
-	UpdateUser(ctx context.Context, input models.User) models.ErrService
-	DeleteUser(ctx context.Context, userId int) models.ErrService
-	GetUserById(ctx context.Context, userId int) (models.User, models.ErrService)
-	GetAllUsers(ctx context.Context, tagId int, limit int, offset int) ([]models.User, models.ErrService)
+	AddUser(ctx context.Context, input UserAddInput) (models.Tokens, error)
+	RefreshTokens(ctx context.Context, refreshToken string) (models.Tokens, error)
+	Logout(ctx context.Context, sessionId int) error
+	ValidateSession(ctx context.Context, userId int, sessionId int, isAdmin bool) error
+	UpdateUser(ctx context.Context, input models.User) error
+	DeleteUser(ctx context.Context, userId int) error
+	GetUserById(ctx context.Context, userId int) (models.User, error)
+	GetUserTags(ctx context.Context, userId int) ([]int, error)
+	GetAllUsers(ctx context.Context, tagsIds []int, page models.PageRequest) ([]models.User, models.PageInfo, error)
+}
+
+type Jobs interface {
+	AddBannersDeleteJob(ctx context.Context, featureId int, tagId int) (int, error)
+	GetJob(ctx context.Context, jobId int) (models.Job, error)
+	Run(ctx context.Context)
+}
+
+type Stats interface {
+	RecordImpression(bannerId int)
+	RecordClick(ctx context.Context, bannerId int) error
+	GetBannerStats(ctx context.Context, bannerId int, from time.Time, to time.Time) ([]models.BannerStats, error)
+	Run(ctx context.Context)
+	Flush(ctx context.Context) error
 }
 
 type Services struct {
@@ -41,13 +80,21 @@ type Services struct {
 	Tags     Tags
 	Features Features
 	Users    Users
+	Jobs     Jobs
+	Stats    Stats
 }
 
-func NewServices(repos *repository.Repositories, tokenManager auth.TokenManager, cache cache.Cache) *Services {
+func NewServices(repos *repository.Repositories, tokenManager auth.TokenManager, cache cache.Cache,
+	events pubsub.PubSub, logger zerolog.Logger, cfg *config.Config) *Services {
+	stats := NewStatsService(repos.Stats, logger, cfg.Stats)
+
 	return &Services{
-		Banners:  NewBannersService(repos.Banners, cache),
-		Tags:     NewTagsService(repos.Tags),
-		Features: NewFeaturesService(repos.Features),
-		Users:    NewUsersService(repos.Users, tokenManager),
+		Banners: NewBannersService(repos.Banners, repos.Features, repos.Variants, stats, cache, events, logger,
+			cfg.Banners, cfg.Pagination),
+		Tags:     NewTagsService(repos.Tags, cache, cfg.Pagination),
+		Features: NewFeaturesService(repos.Features, cache, cfg.Pagination),
+		Users:    NewUsersService(repos.Users, repos.Sessions, tokenManager, cfg.JWT, cfg.Users, cfg.Pagination),
+		Jobs:     NewJobsService(repos.Jobs, repos.Banners, cache, events, logger, cfg.Jobs),
+		Stats:    stats,
 	}
 }
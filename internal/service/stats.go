@@ -0,0 +1,126 @@
+package service
+
+import (
+	"avito-test2024-spring/internal/config"
+	"avito-test2024-spring/internal/models"
+	"avito-test2024-spring/internal/repository"
+	"context"
+	"github.com/rs/zerolog"
+	"sync"
+	"time"
+)
+
+// statsDays is the maximum number of days in one stats request
+const statsDays = 366
+
+type StatsService struct {
+	repo   repository.Stats
+	logger zerolog.Logger
+
+	flushInterval time.Duration
+
+	// impressions which are not written to DB yet, per banner and day
+	impressions   map[impressionKey]int
+	impressionsMu sync.Mutex
+}
+
+type impressionKey struct {
+	bannerId int
+	day      string
+}
+
+func NewStatsService(repo repository.Stats, logger zerolog.Logger, cfg config.StatsConfig) *StatsService {
+	return &StatsService{
+		repo:          repo,
+		logger:        logger,
+		flushInterval: cfg.FlushInterval,
+		impressions:   make(map[impressionKey]int),
+	}
+}
+
+// RecordImpression counts impression of banner in memory, it is written to DB by the next flush.
+func (s *StatsService) RecordImpression(bannerId int) {
+	key := impressionKey{bannerId: bannerId, day: statsDay(time.Now())}
+
+	s.impressionsMu.Lock()
+	s.impressions[key]++
+	s.impressionsMu.Unlock()
+}
+
+func (s *StatsService) RecordClick(ctx context.Context, bannerId int) error {
+	if bannerId <= 0 {
+		return models.NewValidationError("banner id must be greater than 0")
+	}
+
+	return s.repo.AddClick(ctx, bannerId, statsDay(time.Now()))
+}
+
+func (s *StatsService) GetBannerStats(ctx context.Context, bannerId int, from time.Time,
+	to time.Time) ([]models.BannerStats, error) {
+	if bannerId <= 0 {
+		return nil, models.NewValidationError("banner id must be greater than 0")
+	}
+
+	if to.Before(from) {
+		return nil, models.NewValidationError("from must be before to")
+	}
+
+	if to.Sub(from) >= statsDays*24*time.Hour {
+		return nil, models.NewValidationError("stats can be requested for at most 366 days")
+	}
+
+	return s.repo.GetStats(ctx, bannerId, from, to)
+}
+
+// Run flushes buffered impressions to DB every flushInterval until ctx is done.
+func (s *StatsService) Run(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(s.flushInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.Flush(ctx); err != nil {
+					s.logger.Error().Err(err).Msg("error occurred while flushing banners impressions")
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Flush writes buffered impressions to DB in one batch. If write fails, impressions are kept for the next flush.
+func (s *StatsService) Flush(ctx context.Context) error {
+	s.impressionsMu.Lock()
+	buffered := s.impressions
+	s.impressions = make(map[impressionKey]int)
+	s.impressionsMu.Unlock()
+
+	if len(buffered) == 0 {
+		return nil
+	}
+
+	impressions := make([]models.BannerStats, 0, len(buffered))
+	for key, count := range buffered {
+		impressions = append(impressions, models.BannerStats{BannerId: key.bannerId, Day: key.day, Impressions: count})
+	}
+
+	err := s.repo.AddImpressions(ctx, impressions)
+	if err != nil {
+		s.impressionsMu.Lock()
+		for key, count := range buffered {
+			s.impressions[key] += count
+		}
+		s.impressionsMu.Unlock()
+
+		return err
+	}
+
+	return nil
+}
+
+func statsDay(t time.Time) string {
+	return t.UTC().Format(time.DateOnly)
+}
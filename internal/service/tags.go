@@ -1,63 +1,106 @@
 package service
 
 import (
+	"avito-test2024-spring/internal/config"
 	"avito-test2024-spring/internal/models"
 	"avito-test2024-spring/internal/repository"
+	"avito-test2024-spring/pkg/cache"
 	"context"
-	"net/http"
 	"strings"
 )
 
 type TagsService struct {
-	repo repository.Tags
+	repo  repository.Tags
+	cache cache.Cache
+	pager pager
 }
 
-func NewTagsService(repo repository.Tags) *TagsService {
+func NewTagsService(repo repository.Tags, cache cache.Cache, pagination config.PaginationConfig) *TagsService {
 	return &TagsService{
-		repo: repo,
+		repo:  repo,
+		cache: cache,
+		pager: newPager(pagination),
 	}
 }
 
-func (s *TagsService) AddTag(ctx context.Context) (int, models.ErrService) {
+func (s *TagsService) AddTag(ctx context.Context, metadata models.Metadata) (int, error) {
+	err := metadata.Normalize()
+	if err != nil {
+		return -1, models.NewValidationError(err.Error())
+	}
 
-	tagId, err := s.repo.Create(ctx)
+	tagId, err := s.repo.Create(ctx, metadata)
 	if err != nil {
-		return -1, models.NewErrorService(http.StatusInternalServerError, err.Error())
+		return -1, err
 	}
 
-	return tagId, models.ErrService{}
+	return tagId, nil
 }
 
-func (s *TagsService) DeleteTag(ctx context.Context, tagId int) models.ErrService {
-	if tagId <= 0 {
-		return models.NewErrorService(http.StatusBadRequest, "tag_id must be greater than 0")
+// UpdateTag changes metadata of the tag, fields missing in the update are kept.
+func (s *TagsService) UpdateTag(ctx context.Context, tagId int, update models.MetadataUpdate) error {
+	tag, err := s.GetTag(ctx, tagId)
+	if err != nil {
+		return err
 	}
 
-	err := s.repo.Delete(ctx, tagId)
+	update.Apply(&tag.Metadata)
+
+	err = tag.Normalize()
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			return models.NewErrorService(http.StatusNotFound, err.Error())
-		}
+		return models.NewValidationError(err.Error())
+	}
+
+	return s.repo.Update(ctx, tag)
+}
+
+func (s *TagsService) GetTag(ctx context.Context, tagId int) (models.Tag, error) {
+	if tagId <= 0 {
+		return models.Tag{}, models.NewValidationError("tag_id must be greater than 0")
+	}
+
+	return s.repo.GetTagById(ctx, tagId)
+}
 
-		return models.NewErrorService(http.StatusInternalServerError, err.Error())
+func (s *TagsService) GetTagByName(ctx context.Context, name string) (models.Tag, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return models.Tag{}, models.NewValidationError("name must not be empty")
 	}
 
-	return models.ErrService{}
+	return s.repo.GetTagByName(ctx, name)
 }
 
-func (s *TagsService) GetAllTags(ctx context.Context, limit int, offset int) ([]models.Tag, models.ErrService) {
-	if limit < 0 {
-		return nil, models.NewErrorService(http.StatusBadRequest, "limit must be greater than 0")
+func (s *TagsService) DeleteTag(ctx context.Context, tagId int) error {
+	if tagId <= 0 {
+		return models.NewValidationError("tag_id must be greater than 0")
 	}
 
-	if offset < 0 {
-		return nil, models.NewErrorService(http.StatusBadRequest, "offset must be greater or equal to 0")
+	err := s.repo.Delete(ctx, tagId)
+	if err != nil {
+		return err
 	}
 
-	tags, err := s.repo.GetAllTags(ctx, limit, offset)
+	err = s.cache.DeleteByTag(tagId)
 	if err != nil {
-		return nil, models.NewErrorService(http.StatusInternalServerError, err.Error())
+		return err
 	}
 
-	return tags, models.ErrService{}
+	return nil
+}
+
+func (s *TagsService) GetAllTags(ctx context.Context, page models.PageRequest) ([]models.Tag, models.PageInfo, error) {
+	cursor, limit, err := s.pager.parse(page)
+	if err != nil {
+		return nil, models.PageInfo{}, err
+	}
+
+	tags, total, err := s.repo.GetAllTags(ctx, cursor.AfterId, limit+1)
+	if err != nil {
+		return nil, models.PageInfo{}, err
+	}
+
+	tags, next := cutPage(tags, limit, func(t models.Tag) models.Cursor { return idCursor(t.ID) })
+
+	return tags, models.PageInfo{NextCursor: next, Total: total}, nil
 }
@@ -1,126 +1,396 @@
 package service
 
 import (
+	"avito-test2024-spring/internal/config"
 	"avito-test2024-spring/internal/models"
 	"avito-test2024-spring/internal/repository"
 	"avito-test2024-spring/pkg/auth"
 	"context"
-	"net/http"
+	"crypto/sha256"
+	"errors"
+	"fmt"
 	"strconv"
-	"strings"
+	"sync"
 	"time"
 )
 
+const (
+	SessionCheckNone  = "none"
+	SessionCheckDB    = "db"
+	SessionCheckCache = "cache"
+)
+
 type UsersService struct {
 	repo         repository.Users
+	sessionsRepo repository.Sessions
 	tokenManager auth.TokenManager
+
+	accessTokenTTL  time.Duration
+	refreshTokenTTL time.Duration
+
+	sessionCheck    string
+	sessionCacheTTL time.Duration
+	// sessions validated against DB by session id, used in SessionCheckCache mode
+	checkedSessions   map[int]checkedSession
+	checkedSessionsMu sync.Mutex
+	// tags of users loaded from DB with the time of load, kept for tagsCacheTTL
+	usersTags    map[int]loadedUserTags
+	usersTagsMu  sync.Mutex
+	tagsCacheTTL time.Duration
+
+	pager pager
 }
 
-func NewUsersService(repo repository.Users, tokenManager auth.TokenManager) *UsersService {
+func NewUsersService(repo repository.Users, sessionsRepo repository.Sessions, tokenManager auth.TokenManager,
+	cfg config.JWTConfig, users config.UsersConfig, pagination config.PaginationConfig) *UsersService {
 	return &UsersService{
-		repo:         repo,
-		tokenManager: tokenManager,
+		repo:            repo,
+		sessionsRepo:    sessionsRepo,
+		tokenManager:    tokenManager,
+		accessTokenTTL:  cfg.AccessTokenTTL,
+		refreshTokenTTL: cfg.RefreshTokenTTL,
+		sessionCheck:    cfg.SessionCheck,
+		sessionCacheTTL: cfg.SessionCacheTTL,
+		checkedSessions: make(map[int]checkedSession),
+		usersTags:       make(map[int]loadedUserTags),
+		tagsCacheTTL:    users.TagsCacheTTL,
+		pager:           newPager(pagination),
 	}
 }
 
+type checkedSession struct {
+	userId int
+	// isAdmin is the role in access token which was checked against the role of the user in DB
+	isAdmin   bool
+	checkedAt time.Time
+}
+
+type loadedUserTags struct {
+	tagsIds  []int
+	loadedAt time.Time
+}
+
 type UserAddInput struct {
 	IsAdmin bool
-	TagId   int
+	// TagsIds are ordered by priority
+	TagsIds []int
 }
 
-func (s *UsersService) AddUser(ctx context.Context, input UserAddInput) (string, models.ErrService) {
+func (s *UsersService) AddUser(ctx context.Context, input UserAddInput) (models.Tokens, error) {
 	user := models.User{
-		TagId:   input.TagId,
+		TagsIds: input.TagsIds,
 		IsAdmin: input.IsAdmin,
 	}
 
-	if user.TagId < 0 {
-		return "", models.NewErrorService(http.StatusBadRequest, "users tag_id must be greater or equal to 0")
+	err := user.ValidateTags()
+	if err != nil {
+		return models.Tokens{}, models.NewValidationError(err.Error())
 	}
 
 	userId, err := s.repo.Create(ctx, user)
 	if err != nil {
-		return "", models.NewErrorService(http.StatusInternalServerError, err.Error())
+		return models.Tokens{}, err
 	}
 
-	accessToken, err := s.tokenManager.NewJWT(strconv.FormatInt(int64(userId), 10), 2*time.Second)
+	user.Id = userId
+
+	tokens, err := s.createSession(ctx, user)
 	if err != nil {
-		return "", models.NewErrorService(http.StatusInternalServerError, err.Error())
+		return models.Tokens{}, err
 	}
 
-	return accessToken, models.ErrService{}
+	return tokens, nil
 }
 
-func (s *UsersService) UpdateUser(ctx context.Context, input models.User) models.ErrService {
-	if input.Id <= 0 {
-		return models.NewErrorService(http.StatusBadRequest, "users_id must be greater than 0")
+func (s *UsersService) RefreshTokens(ctx context.Context, refreshToken string) (models.Tokens, error) {
+	if refreshToken == "" {
+		return models.Tokens{}, models.NewValidationError("empty refresh_token field")
 	}
 
-	if input.TagId < 0 {
-		return models.NewErrorService(http.StatusBadRequest, "users tag_id must be greater or equal to 0")
+	session, err := s.sessionsRepo.GetSessionByRefreshToken(ctx, hashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return models.Tokens{}, models.NewUnauthorizedError("refresh token is invalid")
+		}
+
+		return models.Tokens{}, err
 	}
 
-	err := s.repo.Update(ctx, input)
+	if session.ExpiresAt.Before(time.Now()) {
+		s.sessionsRepo.Delete(ctx, session.ID)
+		return models.Tokens{}, models.NewUnauthorizedError("refresh token is expired")
+	}
+
+	user, err := s.repo.GetUserById(ctx, session.UserId)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return models.Tokens{}, models.NewUnauthorizedError("refresh token is invalid")
+		}
+
+		return models.Tokens{}, err
+	}
+
+	newRefreshToken, err := s.tokenManager.NewRefreshToken()
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			return models.NewErrorService(http.StatusNotFound, err.Error())
+		return models.Tokens{}, err
+	}
+
+	oldRefreshToken := session.RefreshToken
+	session.RefreshToken = hashToken(newRefreshToken)
+	session.ExpiresAt = time.Now().Add(s.refreshTokenTTL)
+
+	err = s.sessionsRepo.Update(ctx, session, oldRefreshToken)
+	if err != nil {
+		// session is deleted or its token is already rotated by a concurrent refresh
+		if errors.Is(err, models.ErrNotFound) {
+			return models.Tokens{}, models.NewUnauthorizedError("refresh token is invalid")
 		}
 
-		return models.NewErrorService(http.StatusInternalServerError, err.Error())
+		return models.Tokens{}, err
+	}
+
+	accessToken, err := s.newAccessToken(user, session.ID)
+	if err != nil {
+		return models.Tokens{}, err
 	}
 
-	return models.ErrService{}
+	return models.Tokens{AccessToken: accessToken, RefreshToken: newRefreshToken}, nil
 }
 
-func (s *UsersService) DeleteUser(ctx context.Context, userId int) models.ErrService {
-	if userId <= 0 {
-		return models.NewErrorService(http.StatusBadRequest, "users_id must be greater than 0")
+func (s *UsersService) Logout(ctx context.Context, sessionId int) error {
+	s.checkedSessionsMu.Lock()
+	delete(s.checkedSessions, sessionId)
+	s.checkedSessionsMu.Unlock()
+
+	err := s.sessionsRepo.Delete(ctx, sessionId)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return models.NewUnauthorizedError("Пользователь не авторизован")
+		}
+
+		return err
 	}
 
-	err := s.repo.Delete(ctx, userId)
+	return nil
+}
+
+// ValidateSession checks that session of access token was not revoked by logout or user deletion and that role
+// of the user was not changed after the token was issued, so demoted admin loses admin rights before the token expires.
+// Depending on config the check is skipped, made against DB on every call or cached for a short time.
+func (s *UsersService) ValidateSession(ctx context.Context, userId int, sessionId int, isAdmin bool) error {
+	switch s.sessionCheck {
+	case SessionCheckNone:
+		return nil
+	case SessionCheckCache:
+		s.checkedSessionsMu.Lock()
+		checked, ok := s.checkedSessions[sessionId]
+		s.checkedSessionsMu.Unlock()
+
+		if ok && checked.isAdmin == isAdmin && time.Since(checked.checkedAt) < s.sessionCacheTTL {
+			return nil
+		}
+	}
+
+	session, err := s.sessionsRepo.GetSessionById(ctx, sessionId)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return models.NewUnauthorizedError("Пользователь не авторизован")
+		}
+
+		return err
+	}
+
+	if session.UserId != userId || session.ExpiresAt.Before(time.Now()) {
+		return models.NewUnauthorizedError("Пользователь не авторизован")
+	}
+
+	user, err := s.repo.GetUserById(ctx, userId)
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			return models.NewErrorService(http.StatusNotFound, err.Error())
+		if errors.Is(err, models.ErrNotFound) {
+			return models.NewUnauthorizedError("Пользователь не авторизован")
+		}
+
+		return err
+	}
+
+	// refreshed access token gets the current role
+	if user.IsAdmin != isAdmin {
+		return models.NewUnauthorizedError("role of the user has changed, tokens must be refreshed")
+	}
+
+	if s.sessionCheck == SessionCheckCache {
+		s.checkedSessionsMu.Lock()
+		for id, checked := range s.checkedSessions {
+			if time.Since(checked.checkedAt) >= s.sessionCacheTTL {
+				delete(s.checkedSessions, id)
+			}
 		}
+		s.checkedSessions[sessionId] = checkedSession{userId: userId, isAdmin: isAdmin, checkedAt: time.Now()}
+		s.checkedSessionsMu.Unlock()
 	}
 
-	return models.ErrService{}
+	return nil
 }
 
-func (s *UsersService) GetUserById(ctx context.Context, userId int) (models.User, models.ErrService) {
-	if userId <= 0 {
-		return models.User{}, models.NewErrorService(http.StatusBadRequest, "users_id must be greater than 0")
+// GetUserTags returns tags of the user stored in profile ordered by priority, the first tag is the most important.
+// Tags are kept in memory for tagsCacheTTL, so user banners served from cache don't need DB queries.
+// Tags changed by UpdateUser are applied at once on this instance and after the TTL on others.
+func (s *UsersService) GetUserTags(ctx context.Context, userId int) ([]int, error) {
+	s.usersTagsMu.Lock()
+	loaded, ok := s.usersTags[userId]
+	s.usersTagsMu.Unlock()
+
+	if ok && time.Since(loaded.loadedAt) < s.tagsCacheTTL {
+		return loaded.tagsIds, nil
 	}
 
 	user, err := s.repo.GetUserById(ctx, userId)
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			return models.User{}, models.NewErrorService(http.StatusNotFound, err.Error())
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.NewUnauthorizedError("Пользователь не авторизован")
 		}
 
-		return models.User{}, models.NewErrorService(http.StatusInternalServerError, err.Error())
+		return nil, err
 	}
 
-	return user, models.ErrService{}
+	tagsIds := user.TagsIds
+
+	s.usersTagsMu.Lock()
+	for id, loaded := range s.usersTags {
+		if time.Since(loaded.loadedAt) >= s.tagsCacheTTL {
+			delete(s.usersTags, id)
+		}
+	}
+	s.usersTags[userId] = loadedUserTags{tagsIds: tagsIds, loadedAt: time.Now()}
+	s.usersTagsMu.Unlock()
+
+	return tagsIds, nil
 }
 
-func (s *UsersService) GetAllUsers(ctx context.Context, tagId int, limit int, offset int) ([]models.User, models.ErrService) {
-	if limit < 0 {
-		return nil, models.NewErrorService(http.StatusBadRequest, "limit must be greater than 0")
+func (s *UsersService) forgetUserTags(userId int) {
+	s.usersTagsMu.Lock()
+	delete(s.usersTags, userId)
+	s.usersTagsMu.Unlock()
+}
+
+// forgetUserSessions drops checked sessions of the user, so their tokens are checked against the changed user
+// on the next request.
+func (s *UsersService) forgetUserSessions(userId int) {
+	s.checkedSessionsMu.Lock()
+	for id, checked := range s.checkedSessions {
+		if checked.userId == userId {
+			delete(s.checkedSessions, id)
+		}
 	}
+	s.checkedSessionsMu.Unlock()
+}
 
-	if offset < 0 {
-		return nil, models.NewErrorService(http.StatusBadRequest, "offset must be greater or equal to 0")
+func (s *UsersService) createSession(ctx context.Context, user models.User) (models.Tokens, error) {
+	refreshToken, err := s.tokenManager.NewRefreshToken()
+	if err != nil {
+		return models.Tokens{}, err
 	}
 
-	if tagId < 0 {
-		return nil, models.NewErrorService(http.StatusBadRequest, "users tag_id must be greater or equal to 0")
+	sessionId, err := s.sessionsRepo.Create(ctx, models.Session{
+		UserId:       user.Id,
+		RefreshToken: hashToken(refreshToken),
+		ExpiresAt:    time.Now().Add(s.refreshTokenTTL),
+		CreatedAt:    time.Now(),
+	})
+	if err != nil {
+		return models.Tokens{}, err
 	}
 
-	users, err := s.repo.GetAllUsers(ctx, tagId, limit, offset)
+	accessToken, err := s.newAccessToken(user, sessionId)
 	if err != nil {
-		return nil, models.NewErrorService(http.StatusInternalServerError, err.Error())
+		return models.Tokens{}, err
 	}
 
-	return users, models.ErrService{}
+	return models.Tokens{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+func (s *UsersService) newAccessToken(user models.User, sessionId int) (string, error) {
+	return s.tokenManager.NewJWT(auth.Claims{
+		UserId:    strconv.Itoa(user.Id),
+		SessionId: strconv.Itoa(sessionId),
+		IsAdmin:   user.IsAdmin,
+	}, s.accessTokenTTL)
+}
+
+// refresh tokens are stored hashed, so leaked sessions table doesn't give valid tokens
+func hashToken(token string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(token)))
+}
+
+func (s *UsersService) UpdateUser(ctx context.Context, input models.User) error {
+	if input.Id <= 0 {
+		return models.NewValidationError("users_id must be greater than 0")
+	}
+
+	err := input.ValidateTags()
+	if err != nil {
+		return models.NewValidationError(err.Error())
+	}
+
+	err = s.repo.Update(ctx, input)
+	if err != nil {
+		return err
+	}
+
+	s.forgetUserTags(input.Id)
+	s.forgetUserSessions(input.Id)
+
+	return nil
+}
+
+func (s *UsersService) DeleteUser(ctx context.Context, userId int) error {
+	if userId <= 0 {
+		return models.NewValidationError("users_id must be greater than 0")
+	}
+
+	err := s.repo.Delete(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	s.forgetUserTags(userId)
+	s.forgetUserSessions(userId)
+
+	return nil
+}
+
+func (s *UsersService) GetUserById(ctx context.Context, userId int) (models.User, error) {
+	if userId <= 0 {
+		return models.User{}, models.NewValidationError("users_id must be greater than 0")
+	}
+
+	user, err := s.repo.GetUserById(ctx, userId)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	return user, nil
+}
+
+// GetAllUsers returns page of users, if tagsIds is not empty only users having any of these tags are returned.
+func (s *UsersService) GetAllUsers(ctx context.Context, tagsIds []int, page models.PageRequest) ([]models.User, models.PageInfo, error) {
+	cursor, limit, err := s.pager.parse(page)
+	if err != nil {
+		return nil, models.PageInfo{}, err
+	}
+
+	for _, tagId := range tagsIds {
+		if tagId <= 0 {
+			return nil, models.PageInfo{}, models.NewValidationError("tag_id must be greater than 0")
+		}
+	}
+
+	users, total, err := s.repo.GetAllUsers(ctx, tagsIds, cursor.AfterId, limit+1)
+	if err != nil {
+		return nil, models.PageInfo{}, err
+	}
+
+	users, next := cutPage(users, limit, func(u models.User) models.Cursor { return idCursor(u.Id) })
+
+	return users, models.PageInfo{NextCursor: next, Total: total}, nil
 }
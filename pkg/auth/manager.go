@@ -1,16 +1,23 @@
 package auth
 
 import (
+	"crypto/rand"
 	"errors"
 	"fmt"
 	"github.com/golang-jwt/jwt/v5"
-	"math/rand"
 	"time"
 )
 
+// Claims are the user data signed into access token, so they can be trusted without a DB lookup.
+type Claims struct {
+	UserId    string
+	SessionId string
+	IsAdmin   bool
+}
+
 type TokenManager interface {
-	NewJWT(userId string, ttl time.Duration) (string, error)
-	Parse(accessToken string) (string, error)
+	NewJWT(claims Claims, ttl time.Duration) (string, error)
+	Parse(accessToken string) (Claims, error)
 	NewRefreshToken() (string, error)
 }
 
@@ -18,6 +25,11 @@ type Manager struct {
 	signingKey string
 }
 
+type tokenClaims struct {
+	jwt.RegisteredClaims
+	IsAdmin bool `json:"is_admin"`
+}
+
 func NewManager(signingKey string) (*Manager, error) {
 	if signingKey == "" {
 		return nil, errors.New("empty signing key")
@@ -26,41 +38,44 @@ func NewManager(signingKey string) (*Manager, error) {
 	return &Manager{signingKey: signingKey}, nil
 }
 
-func (m *Manager) NewJWT(userId string, ttl time.Duration) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
-		//ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
-		Subject: userId,
+func (m *Manager) NewJWT(claims Claims, ttl time.Duration) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, tokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Subject:   claims.UserId,
+			ID:        claims.SessionId,
+		},
+		IsAdmin: claims.IsAdmin,
 	})
 
 	return token.SignedString([]byte(m.signingKey))
 }
 
-func (m *Manager) Parse(accessToken string) (string, error) {
-	token, err := jwt.Parse(accessToken, func(token *jwt.Token) (i interface{}, err error) {
+func (m *Manager) Parse(accessToken string) (Claims, error) {
+	var claims tokenClaims
+
+	_, err := jwt.ParseWithClaims(accessToken, &claims, func(token *jwt.Token) (i interface{}, err error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 		return []byte(m.signingKey), nil
-	})
+	}, jwt.WithExpirationRequired())
 	if err != nil {
-		return "", err
+		return Claims{}, err
 	}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		return "", fmt.Errorf("error get user claims from token")
-	}
-
-	return claims.GetSubject()
+	return Claims{
+		UserId:    claims.Subject,
+		SessionId: claims.ID,
+		IsAdmin:   claims.IsAdmin,
+	}, nil
 }
 
 func (m *Manager) NewRefreshToken() (string, error) {
 	b := make([]byte, 32)
 
-	s := rand.NewSource(time.Now().Unix())
-	r := rand.New(s)
-
-	_, err := r.Read(b)
+	_, err := rand.Read(b)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestManager_NewJWT_Parse(t *testing.T) {
+	manager, err := NewManager("test")
+	require.NoError(t, err)
+
+	claims := Claims{
+		UserId:    "1",
+		SessionId: "2",
+		IsAdmin:   true,
+	}
+
+	token, err := manager.NewJWT(claims, time.Minute)
+	require.NoError(t, err)
+
+	parsed, err := manager.Parse(token)
+	require.NoError(t, err)
+	require.Equal(t, claims, parsed)
+
+	t.Run("Expired_Token", func(t *testing.T) {
+		token, err := manager.NewJWT(claims, -time.Minute)
+		require.NoError(t, err)
+
+		_, err = manager.Parse(token)
+		require.Error(t, err)
+	})
+
+	t.Run("Wrong_Signing_Key", func(t *testing.T) {
+		other, err := NewManager("other")
+		require.NoError(t, err)
+
+		_, err = other.Parse(token)
+		require.Error(t, err)
+	})
+}
@@ -1,9 +1,74 @@
 package cache
 
-import "avito-test2024-spring/internal/models"
+import (
+	"avito-test2024-spring/internal/config"
+	"avito-test2024-spring/internal/models"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	BackendRedis  = "redis"
+	BackendMemory = "memory"
+	BackendTiered = "tiered"
+)
+
+var ErrNotFound = errors.New("not found")
+
+// Entry is a cached banner together with id of the banner it belongs to, time when it expires
+// and entity tag of its content.
+type Entry struct {
+	Banner    models.Banner
+	BannerId  int
+	ExpiresAt time.Time
+	ETag      string
+}
+
+// Key identifies cached banner of tag, feature and variant of A/B experiment (0 if there is no experiment).
+type Key struct {
+	TagId     int
+	FeatureId int
+	Variant   int
+}
 
 type Cache interface {
-	Set(banner models.Banner, tagId int, featureId int, bannerId int) error
-	Get(tagId int, featureId int) (models.Banner, error)
+	// Set stores banner for tag, feature and variant of A/B experiment (0 if there is no experiment).
+	// Non-zero expiresAt limits entry lifetime below the configured TTL.
+	Set(banner models.Banner, tagId int, featureId int, variant int, bannerId int, expiresAt time.Time) error
+	Get(tagId int, featureId int, variant int) (Entry, error)
+	// GetMany returns entries found for keys, missing keys are absent from the result.
+	GetMany(keys []Key) (map[Key]Entry, error)
 	Delete(bannerId int) error
+	DeleteByTag(tagId int) error
+	DeleteByFeature(featureId int) error
+	// TTL returns lifetime of entries stored without expiresAt.
+	TTL() time.Duration
+}
+
+// NewCache creates cache backend selected in config: redis, in-process memory or memory in front of redis.
+func NewCache(cfg config.CacheConfig, redisCfg config.RedisConfig) (Cache, error) {
+	switch cfg.Backend {
+	case BackendRedis:
+		return NewRedisCache(redisCfg), nil
+	case BackendMemory:
+		return NewMemoryCache(cfg.Size, cfg.TTL), nil
+	case BackendTiered:
+		return NewTieredCache(NewMemoryCache(cfg.Size, cfg.TTL), NewRedisCache(redisCfg)), nil
+	default:
+		return nil, errors.New(fmt.Sprintf("unknown cache backend %q", cfg.Backend))
+	}
+}
+
+func bannerKey(tagId int, featureId int, variant int) string {
+	return fmt.Sprintf("tag_id:%v:feature_id:%v:variant:%v", tagId, featureId, variant)
+}
+
+// entryTTL returns lifetime of entry which expires after ttl, but not later than expiresAt if it is set.
+func entryTTL(ttl time.Duration, expiresAt time.Time) time.Duration {
+	if expiresAt.IsZero() {
+		return ttl
+	}
+
+	return min(ttl, time.Until(expiresAt))
 }
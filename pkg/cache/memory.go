@@ -0,0 +1,176 @@
+package cache
+
+import (
+	"avito-test2024-spring/internal/models"
+	"container/list"
+	"sync"
+	"time"
+)
+
+// MemoryCache is an in-process LRU cache with TTL. When size is exceeded the least recently used banner is evicted.
+type MemoryCache struct {
+	mu sync.Mutex
+
+	size int
+	ttl  time.Duration
+
+	items map[string]*list.Element
+	// front of the list is the most recently used item
+	order *list.List
+	// banner id -> keys of items with this banner, used by Delete
+	banners map[int]map[string]struct{}
+}
+
+type memoryItem struct {
+	key       string
+	banner    models.Banner
+	bannerId  int
+	tagId     int
+	featureId int
+	expiresAt time.Time
+	etag      string
+}
+
+func NewMemoryCache(size int, ttl time.Duration) *MemoryCache {
+	return &MemoryCache{
+		size:    size,
+		ttl:     ttl,
+		items:   make(map[string]*list.Element),
+		order:   list.New(),
+		banners: make(map[int]map[string]struct{}),
+	}
+}
+
+func (c *MemoryCache) Set(banner models.Banner, tagId int, featureId int, variant int, bannerId int,
+	expiresAt time.Time) error {
+	key := bannerKey(tagId, featureId, variant)
+
+	now := time.Now()
+	if expiresAt.IsZero() || expiresAt.After(now.Add(c.ttl)) {
+		expiresAt = now.Add(c.ttl)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+
+	// banner is already expired, so it is not stored
+	if !expiresAt.After(now) {
+		return nil
+	}
+
+	el := c.order.PushFront(&memoryItem{
+		key:       key,
+		banner:    banner,
+		bannerId:  bannerId,
+		tagId:     tagId,
+		featureId: featureId,
+		expiresAt: expiresAt,
+		etag:      models.BannerETag(banner),
+	})
+	c.items[key] = el
+
+	if c.banners[bannerId] == nil {
+		c.banners[bannerId] = make(map[string]struct{})
+	}
+	c.banners[bannerId][key] = struct{}{}
+
+	for c.size > 0 && c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+
+	return nil
+}
+
+func (c *MemoryCache) Get(tagId int, featureId int, variant int) (Entry, error) {
+	key := bannerKey(tagId, featureId, variant)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return Entry{}, ErrNotFound
+	}
+
+	item := el.Value.(*memoryItem)
+	if time.Now().After(item.expiresAt) {
+		c.remove(el)
+		return Entry{}, ErrNotFound
+	}
+
+	c.order.MoveToFront(el)
+
+	return Entry{Banner: item.banner, BannerId: item.bannerId, ExpiresAt: item.expiresAt, ETag: item.etag}, nil
+}
+
+func (c *MemoryCache) GetMany(keys []Key) (map[Key]Entry, error) {
+	entries := make(map[Key]Entry, len(keys))
+
+	for _, key := range keys {
+		entry, err := c.Get(key.TagId, key.FeatureId, key.Variant)
+		if err != nil {
+			continue
+		}
+
+		entries[key] = entry
+	}
+
+	return entries, nil
+}
+
+func (c *MemoryCache) Delete(bannerId int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key := range c.banners[bannerId] {
+		c.remove(c.items[key])
+	}
+
+	return nil
+}
+
+func (c *MemoryCache) DeleteByTag(tagId int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, el := range c.items {
+		if el.Value.(*memoryItem).tagId == tagId {
+			c.remove(el)
+		}
+	}
+
+	return nil
+}
+
+func (c *MemoryCache) DeleteByFeature(featureId int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, el := range c.items {
+		if el.Value.(*memoryItem).featureId == featureId {
+			c.remove(el)
+		}
+	}
+
+	return nil
+}
+
+func (c *MemoryCache) TTL() time.Duration {
+	return c.ttl
+}
+
+func (c *MemoryCache) remove(el *list.Element) {
+	item := el.Value.(*memoryItem)
+
+	c.order.Remove(el)
+	delete(c.items, item.key)
+
+	delete(c.banners[item.bannerId], item.key)
+	if len(c.banners[item.bannerId]) == 0 {
+		delete(c.banners, item.bannerId)
+	}
+}
@@ -0,0 +1,122 @@
+package cache
+
+import (
+	"avito-test2024-spring/internal/models"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestMemoryCache(t *testing.T) {
+	t.Run("Set_Get_Delete", func(t *testing.T) {
+		c := NewMemoryCache(10, time.Minute)
+
+		require.NoError(t, c.Set(models.Banner(`{"title":"a"}`), 1, 1, 0, 10, time.Time{}))
+		require.NoError(t, c.Set(models.Banner(`{"title":"a"}`), 2, 1, 0, 10, time.Time{}))
+		require.NoError(t, c.Set(models.Banner(`{"title":"b"}`), 1, 2, 0, 20, time.Time{}))
+
+		entry, err := c.Get(1, 1, 0)
+		require.NoError(t, err)
+		require.Equal(t, 10, entry.BannerId)
+		require.JSONEq(t, `{"title":"a"}`, string(entry.Banner))
+
+		require.NoError(t, c.Delete(10))
+
+		_, err = c.Get(1, 1, 0)
+		require.ErrorIs(t, err, ErrNotFound)
+		_, err = c.Get(2, 1, 0)
+		require.ErrorIs(t, err, ErrNotFound)
+
+		entry, err = c.Get(1, 2, 0)
+		require.NoError(t, err)
+		require.Equal(t, 20, entry.BannerId)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		c := NewMemoryCache(10, time.Millisecond)
+
+		require.NoError(t, c.Set(models.Banner(`{}`), 1, 1, 0, 10, time.Time{}))
+		time.Sleep(5 * time.Millisecond)
+
+		_, err := c.Get(1, 1, 0)
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("Evict_Least_Recently_Used", func(t *testing.T) {
+		c := NewMemoryCache(2, time.Minute)
+
+		require.NoError(t, c.Set(models.Banner(`{}`), 1, 1, 0, 10, time.Time{}))
+		require.NoError(t, c.Set(models.Banner(`{}`), 2, 1, 0, 20, time.Time{}))
+
+		_, err := c.Get(1, 1, 0)
+		require.NoError(t, err)
+
+		require.NoError(t, c.Set(models.Banner(`{}`), 3, 1, 0, 30, time.Time{}))
+
+		_, err = c.Get(2, 1, 0)
+		require.ErrorIs(t, err, ErrNotFound)
+		_, err = c.Get(1, 1, 0)
+		require.NoError(t, err)
+		_, err = c.Get(3, 1, 0)
+		require.NoError(t, err)
+	})
+
+	t.Run("Overwrite_With_Other_Banner", func(t *testing.T) {
+		c := NewMemoryCache(10, time.Minute)
+
+		require.NoError(t, c.Set(models.Banner(`{}`), 1, 1, 0, 10, time.Time{}))
+		require.NoError(t, c.Set(models.Banner(`{}`), 1, 1, 0, 20, time.Time{}))
+		require.NoError(t, c.Delete(10))
+
+		entry, err := c.Get(1, 1, 0)
+		require.NoError(t, err)
+		require.Equal(t, 20, entry.BannerId)
+	})
+	t.Run("Delete_By_Tag_And_Feature", func(t *testing.T) {
+		c := NewMemoryCache(10, time.Minute)
+
+		require.NoError(t, c.Set(models.Banner(`{}`), 1, 1, 0, 10, time.Time{}))
+		require.NoError(t, c.Set(models.Banner(`{}`), 1, 2, 0, 20, time.Time{}))
+		require.NoError(t, c.Set(models.Banner(`{}`), 2, 2, 0, 30, time.Time{}))
+
+		require.NoError(t, c.DeleteByTag(1))
+
+		_, err := c.Get(1, 1, 0)
+		require.ErrorIs(t, err, ErrNotFound)
+		_, err = c.Get(1, 2, 0)
+		require.ErrorIs(t, err, ErrNotFound)
+		_, err = c.Get(2, 2, 0)
+		require.NoError(t, err)
+
+		require.NoError(t, c.DeleteByFeature(2))
+
+		_, err = c.Get(2, 2, 0)
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+	t.Run("Expires_At", func(t *testing.T) {
+		c := NewMemoryCache(10, time.Minute)
+
+		require.NoError(t, c.Set(models.Banner(`{}`), 1, 1, 0, 10, time.Now().Add(-time.Second)))
+		_, err := c.Get(1, 1, 0)
+		require.ErrorIs(t, err, ErrNotFound)
+
+		expiresAt := time.Now().Add(time.Second)
+		require.NoError(t, c.Set(models.Banner(`{}`), 1, 1, 0, 10, expiresAt))
+		entry, err := c.Get(1, 1, 0)
+		require.NoError(t, err)
+		require.False(t, entry.ExpiresAt.After(expiresAt))
+	})
+
+	t.Run("Get_Many", func(t *testing.T) {
+		c := NewMemoryCache(10, time.Minute)
+
+		require.NoError(t, c.Set(models.Banner(`{"title":"a"}`), 1, 1, 0, 10, time.Time{}))
+		require.NoError(t, c.Set(models.Banner(`{"title":"b"}`), 1, 2, 20, 20, time.Time{}))
+
+		entries, err := c.GetMany([]Key{{TagId: 1, FeatureId: 1}, {TagId: 1, FeatureId: 2, Variant: 20}, {TagId: 1, FeatureId: 3}})
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		require.Equal(t, 10, entries[Key{TagId: 1, FeatureId: 1}].BannerId)
+		require.Equal(t, 20, entries[Key{TagId: 1, FeatureId: 2, Variant: 20}].BannerId)
+	})
+}
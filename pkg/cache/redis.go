@@ -42,23 +42,46 @@ func NewRedisCache(cfg config.RedisConfig) *RedisCache {
 
 // https://pkg.go.dev/github.com/gomodule/redigo/redis#example-Args
 
-func (c *RedisCache) Set(banner models.Banner, tagId int, featureId int, bannerId int) error {
-	key := fmt.Sprintf("tag_id:%v:feature_id:%v", tagId, featureId)
+// deleteIndexedScript deletes all keys stored in the index set and the set itself in one round trip
+var deleteIndexedScript = redis.NewScript(1, `
+local keys = redis.call('SMEMBERS', KEYS[1])
+for _, key in ipairs(keys) do
+	redis.call('DEL', key)
+end
+redis.call('DEL', KEYS[1])
+return #keys
+`)
+
+// Set stores banner and adds its key to banner, tag and feature indexes used for invalidation.
+func (c *RedisCache) Set(banner models.Banner, tagId int, featureId int, variant int, bannerId int,
+	expiresAt time.Time) error {
+	key := bannerKey(tagId, featureId, variant)
+	ttl := int64(c.CacheTTL.Seconds())
 
 	conn := c.ConnPool.Get()
 	defer conn.Close()
 
-	jsonBanner, err := json.Marshal(banner)
-	if err != nil {
+	keyTTL := entryTTL(c.CacheTTL, expiresAt).Milliseconds()
+	if keyTTL <= 0 {
+		// banner is already expired, so it is not stored
+		_, err := conn.Do("DEL", key)
 		return err
 	}
 
-	_, err = conn.Do("HSET", key, "banner_id", bannerId, "content", jsonBanner)
+	jsonBanner, err := json.Marshal(banner)
 	if err != nil {
 		return err
 	}
 
-	_, err = conn.Do("EXPIRE", key, int64(c.CacheTTL.Seconds()))
+	conn.Send("MULTI")
+	conn.Send("HSET", key, "banner_id", bannerId, "content", jsonBanner, "etag", models.BannerETag(banner))
+	conn.Send("PEXPIRE", key, keyTTL)
+	for _, index := range []string{bannerIndexKey(bannerId), tagIndexKey(tagId), featureIndexKey(featureId)} {
+		conn.Send("SADD", index, key)
+		conn.Send("EXPIRE", index, ttl)
+	}
+
+	_, err = conn.Do("EXEC")
 	if err != nil {
 		return err
 	}
@@ -66,71 +89,147 @@ func (c *RedisCache) Set(banner models.Banner, tagId int, featureId int, bannerI
 	return nil
 }
 
-func (c *RedisCache) Get(tagId int, featureId int) (models.Banner, error) {
-	key := fmt.Sprintf("tag_id:%v:feature_id:%v", tagId, featureId)
+func (c *RedisCache) Get(tagId int, featureId int, variant int) (Entry, error) {
+	key := bannerKey(tagId, featureId, variant)
 
 	conn := c.ConnPool.Get()
 	defer conn.Close()
 
-	val, err := conn.Do("HGET", key, "content")
-	if err != nil {
-		return models.Banner{}, err
-	}
-	if val == nil {
-		return models.Banner{}, errors.New("not found")
-	}
-
-	var banner models.Banner
+	conn.Send("MULTI")
+	conn.Send("HMGET", key, "content", "banner_id", "etag")
+	conn.Send("PTTL", key)
 
-	err = json.Unmarshal(val.([]byte), &banner)
+	replies, err := redis.Values(conn.Do("EXEC"))
 	if err != nil {
-		return models.Banner{}, err
+		return Entry{}, err
 	}
 
-	return banner, nil
+	return parseEntry(replies[0], replies[1])
 }
 
-func (c *RedisCache) Delete(bannerId int) error {
+// GetMany pipelines reads of all keys, so they take one round trip to redis.
+func (c *RedisCache) GetMany(keys []Key) (map[Key]Entry, error) {
+	entries := make(map[Key]Entry, len(keys))
+	if len(keys) == 0 {
+		return entries, nil
+	}
+
 	conn := c.ConnPool.Get()
 	defer conn.Close()
 
-	_, err := conn.Do("SELECT", 5)
+	for _, key := range keys {
+		redisKey := bannerKey(key.TagId, key.FeatureId, key.Variant)
+		conn.Send("HMGET", redisKey, "content", "banner_id", "etag")
+		conn.Send("PTTL", redisKey)
+	}
+
+	err := conn.Flush()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	iter := 0
-	keys := make([]string, 0)
-	for {
-		arr, err := redis.Values(conn.Do("SCAN", 5))
+	for _, key := range keys {
+		values, err := conn.Receive()
 		if err != nil {
-			return err
+			return nil, err
 		}
 
-		iter, _ = redis.Int(arr[0], nil)
-		keyBytes, _ := redis.ByteSlices(arr[1], err)
-		for _, b := range keyBytes {
-			keys = append(keys, string(b))
+		ttl, err := conn.Receive()
+		if err != nil {
+			return nil, err
 		}
 
-		if iter == 0 {
-			break
+		entry, err := parseEntry(values, ttl)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			return nil, err
 		}
+
+		entries[key] = entry
 	}
 
-	for _, k := range keys {
-		value, err := redis.Int(conn.Do("HGET", k, "banner_id"))
-		if err != nil {
-			return err
-		}
+	return entries, nil
+}
 
-		if value == bannerId {
-			_, err = conn.Do("DEL", k)
-			if err != nil {
-				return err
-			}
+// parseEntry builds entry from replies of HMGET content banner_id etag and PTTL of the banner key.
+func parseEntry(hmgetReply interface{}, pttlReply interface{}) (Entry, error) {
+	values, err := redis.Values(hmgetReply, nil)
+	if err != nil {
+		return Entry{}, err
+	}
+	if values[0] == nil || values[1] == nil {
+		return Entry{}, ErrNotFound
+	}
+
+	var banner models.Banner
+
+	err = json.Unmarshal(values[0].([]byte), &banner)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	bannerId, err := redis.Int(values[1], nil)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	ttl, err := redis.Int64(pttlReply, nil)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	// entries written before etag was stored get it computed on read
+	etag := models.BannerETag(banner)
+	if values[2] != nil {
+		etag, err = redis.String(values[2], nil)
+		if err != nil {
+			return Entry{}, err
 		}
 	}
 
-	return nil
+	return Entry{
+		Banner:    banner,
+		BannerId:  bannerId,
+		ExpiresAt: time.Now().Add(time.Duration(ttl) * time.Millisecond),
+		ETag:      etag,
+	}, nil
+}
+
+func (c *RedisCache) TTL() time.Duration {
+	return c.CacheTTL
+}
+
+func (c *RedisCache) Delete(bannerId int) error {
+	return c.deleteIndexed(bannerIndexKey(bannerId))
+}
+
+func (c *RedisCache) DeleteByTag(tagId int) error {
+	return c.deleteIndexed(tagIndexKey(tagId))
+}
+
+func (c *RedisCache) DeleteByFeature(featureId int) error {
+	return c.deleteIndexed(featureIndexKey(featureId))
+}
+
+func (c *RedisCache) deleteIndexed(index string) error {
+	conn := c.ConnPool.Get()
+	defer conn.Close()
+
+	_, err := deleteIndexedScript.Do(conn, index)
+
+	return err
+}
+
+func bannerIndexKey(bannerId int) string {
+	return fmt.Sprintf("index:banner_id:%v", bannerId)
+}
+
+func tagIndexKey(tagId int) string {
+	return fmt.Sprintf("index:tag_id:%v", tagId)
+}
+
+func featureIndexKey(featureId int) string {
+	return fmt.Sprintf("index:feature_id:%v", featureId)
 }
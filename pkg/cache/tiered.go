@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"avito-test2024-spring/internal/models"
+	"errors"
+	"time"
+)
+
+// TieredCache reads from the local cache first and falls back to the remote one, filling the local cache on hit.
+// Local cache of other replicas is not invalidated by Delete, so its TTL should be short.
+type TieredCache struct {
+	local  Cache
+	remote Cache
+}
+
+func NewTieredCache(local Cache, remote Cache) *TieredCache {
+	return &TieredCache{
+		local:  local,
+		remote: remote,
+	}
+}
+
+func (c *TieredCache) Set(banner models.Banner, tagId int, featureId int, variant int, bannerId int,
+	expiresAt time.Time) error {
+	if err := c.remote.Set(banner, tagId, featureId, variant, bannerId, expiresAt); err != nil {
+		return err
+	}
+
+	return c.local.Set(banner, tagId, featureId, variant, bannerId, expiresAt)
+}
+
+func (c *TieredCache) Get(tagId int, featureId int, variant int) (Entry, error) {
+	entry, err := c.local.Get(tagId, featureId, variant)
+	if err == nil {
+		return entry, nil
+	}
+
+	if !errors.Is(err, ErrNotFound) {
+		return Entry{}, err
+	}
+
+	entry, err = c.remote.Get(tagId, featureId, variant)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	if err := c.local.Set(entry.Banner, tagId, featureId, variant, entry.BannerId, entry.ExpiresAt); err != nil {
+		return Entry{}, err
+	}
+
+	return entry, nil
+}
+
+// GetMany reads all keys from the local cache and only the missing ones from the remote cache.
+func (c *TieredCache) GetMany(keys []Key) (map[Key]Entry, error) {
+	entries, err := c.local.GetMany(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	missing := make([]Key, 0, len(keys)-len(entries))
+	for _, key := range keys {
+		if _, ok := entries[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+
+	if len(missing) == 0 {
+		return entries, nil
+	}
+
+	remoteEntries, err := c.remote.GetMany(missing)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, entry := range remoteEntries {
+		if err := c.local.Set(entry.Banner, key.TagId, key.FeatureId, key.Variant, entry.BannerId, entry.ExpiresAt); err != nil {
+			return nil, err
+		}
+
+		entries[key] = entry
+	}
+
+	return entries, nil
+}
+
+// TTL is the lifetime of local entries, because reads are served from the local cache.
+func (c *TieredCache) TTL() time.Duration {
+	return c.local.TTL()
+}
+
+func (c *TieredCache) Delete(bannerId int) error {
+	if err := c.local.Delete(bannerId); err != nil {
+		return err
+	}
+
+	return c.remote.Delete(bannerId)
+}
+
+func (c *TieredCache) DeleteByTag(tagId int) error {
+	if err := c.local.DeleteByTag(tagId); err != nil {
+		return err
+	}
+
+	return c.remote.DeleteByTag(tagId)
+}
+
+func (c *TieredCache) DeleteByFeature(featureId int) error {
+	if err := c.local.DeleteByFeature(featureId); err != nil {
+		return err
+	}
+
+	return c.remote.DeleteByFeature(featureId)
+}
@@ -0,0 +1,28 @@
+package cache
+
+import (
+	"avito-test2024-spring/internal/models"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestTieredCache_GetMany(t *testing.T) {
+	local := NewMemoryCache(10, time.Minute)
+	remote := NewMemoryCache(10, time.Minute)
+	c := NewTieredCache(local, remote)
+
+	require.NoError(t, local.Set(models.Banner(`{}`), 1, 1, 0, 10, time.Time{}))
+	require.NoError(t, remote.Set(models.Banner(`{}`), 1, 2, 0, 20, time.Time{}))
+
+	entries, err := c.GetMany([]Key{{TagId: 1, FeatureId: 1}, {TagId: 1, FeatureId: 2}, {TagId: 1, FeatureId: 3}})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, 10, entries[Key{TagId: 1, FeatureId: 1}].BannerId)
+	require.Equal(t, 20, entries[Key{TagId: 1, FeatureId: 2}].BannerId)
+
+	// entries found in the remote cache are copied to the local one
+	entry, err := local.Get(1, 2, 0)
+	require.NoError(t, err)
+	require.Equal(t, 20, entry.BannerId)
+}
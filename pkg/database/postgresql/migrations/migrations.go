@@ -0,0 +1,276 @@
+package migrations
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// file name format is <version>_<name>.<up|down>.sql, e.g. 0001_init.up.sql
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// lockId is a key of postgres advisory lock, so migrations of several app instances don't run concurrently
+const lockId = 20240410
+
+const createMigrationsTable = `create table if not exists schema_migrations (
+    version int not null,
+    name varchar(255) not null,
+    applied_at timestamp not null,
+    primary key (version)
+);`
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Load reads embedded migrations ordered by version. Every migration must have both up and down steps.
+func Load() ([]Migration, error) {
+	entries, err := fs.ReadDir(files, "sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, errors.New(fmt.Sprintf("migration file %v has invalid name", entry.Name()))
+		}
+
+		version, _ := strconv.Atoi(match[1])
+
+		content, err := files.ReadFile("sql/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+
+		if m.Name != match[2] {
+			return nil, errors.New(fmt.Sprintf("migration with version=%v has different names", version))
+		}
+
+		if match[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, errors.New(fmt.Sprintf("migration with version=%v must have up and down steps", m.Version))
+		}
+
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+type Migrator struct {
+	db         *pgxpool.Pool
+	migrations []Migration
+}
+
+func NewMigrator(db *pgxpool.Pool) (*Migrator, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+	}, nil
+}
+
+// Latest returns version of the last migration known to the binary.
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Version returns version of the last migration applied to DB, 0 means that no migrations were applied.
+func (m *Migrator) Version(ctx context.Context) (int, error) {
+	conn, err := m.db.Acquire(ctx)
+	if err != nil {
+		return -1, err
+	}
+	defer conn.Release()
+
+	return m.version(ctx, conn)
+}
+
+// Check returns error if DB schema is newer than the binary.
+func (m *Migrator) Check(ctx context.Context) error {
+	version, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+
+	if version > m.Latest() {
+		return errors.New(fmt.Sprintf("DB schema version=%v is newer than the latest known version=%v", version, m.Latest()))
+	}
+
+	return nil
+}
+
+// Up applies all pending migrations, each in its own transaction. It returns number of applied migrations.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	conn, err := m.lock(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer m.unlock(conn)
+
+	version, err := m.version(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+
+	if version > m.Latest() {
+		return 0, errors.New(fmt.Sprintf("DB schema version=%v is newer than the latest known version=%v", version, m.Latest()))
+	}
+
+	applied := 0
+	for _, migration := range m.migrations {
+		if migration.Version <= version {
+			continue
+		}
+
+		err = m.apply(ctx, conn, migration.Up,
+			`INSERT INTO schema_migrations (version, name, applied_at) VALUES (@version, @name, @appliedAt)`,
+			pgx.NamedArgs{"version": migration.Version, "name": migration.Name, "appliedAt": time.Now()})
+		if err != nil {
+			return applied, errors.New(fmt.Sprintf("migration %v_%v: %v", migration.Version, migration.Name, err))
+		}
+
+		applied++
+	}
+
+	return applied, nil
+}
+
+// Down rolls back the given number of last applied migrations. It returns number of rolled back migrations.
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	conn, err := m.lock(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer m.unlock(conn)
+
+	rolledBack := 0
+	for ; rolledBack < steps; rolledBack++ {
+		version, err := m.version(ctx, conn)
+		if err != nil {
+			return rolledBack, err
+		}
+
+		if version == 0 {
+			break
+		}
+
+		idx := sort.Search(len(m.migrations), func(i int) bool {
+			return m.migrations[i].Version >= version
+		})
+		if idx == len(m.migrations) || m.migrations[idx].Version != version {
+			return rolledBack, errors.New(fmt.Sprintf("migration with version=%v not found", version))
+		}
+
+		migration := m.migrations[idx]
+
+		err = m.apply(ctx, conn, migration.Down,
+			`DELETE FROM schema_migrations WHERE version = @version`,
+			pgx.NamedArgs{"version": migration.Version})
+		if err != nil {
+			return rolledBack, errors.New(fmt.Sprintf("migration %v_%v: %v", migration.Version, migration.Name, err))
+		}
+	}
+
+	return rolledBack, nil
+}
+
+func (m *Migrator) apply(ctx context.Context, conn *pgxpool.Conn, script string, query string, args pgx.NamedArgs) error {
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, script)
+	if err != nil {
+		tx.Rollback(ctx)
+		return err
+	}
+
+	_, err = tx.Exec(ctx, query, args)
+	if err != nil {
+		tx.Rollback(ctx)
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (m *Migrator) version(ctx context.Context, conn *pgxpool.Conn) (int, error) {
+	_, err := conn.Exec(ctx, createMigrationsTable)
+	if err != nil {
+		return -1, err
+	}
+
+	var version int
+
+	err = conn.QueryRow(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	if err != nil {
+		return -1, err
+	}
+
+	return version, nil
+}
+
+func (m *Migrator) lock(ctx context.Context) (*pgxpool.Conn, error) {
+	conn, err := m.db.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, lockId)
+	if err != nil {
+		conn.Release()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+func (m *Migrator) unlock(conn *pgxpool.Conn) {
+	conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, lockId)
+	conn.Release()
+}
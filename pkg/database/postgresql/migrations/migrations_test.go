@@ -0,0 +1,19 @@
+package migrations
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load()
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	for i, m := range migrations {
+		require.Equal(t, i+1, m.Version, "migrations versions must be sequential")
+		require.NotEmpty(t, m.Name)
+		require.NotEmpty(t, m.Up)
+		require.NotEmpty(t, m.Down)
+	}
+}
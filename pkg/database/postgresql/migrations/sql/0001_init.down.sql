@@ -0,0 +1,5 @@
+drop table if exists users;
+drop table if exists banners_tags;
+drop table if exists tags;
+drop table if exists banners;
+drop table if exists features;
@@ -1,6 +1,3 @@
-package dbscripts
-
-const Create = `
 create table if not exists features (
     id bigserial not null,
     primary key (id)
@@ -46,4 +43,4 @@ create table if not exists users (
     constraint fk_tag
     foreign key (fk_tag_id) references tags(id)
         on delete restrict on update restrict
-);`
+);
@@ -0,0 +1,12 @@
+create table if not exists banners_versions (
+    fk_banner_id int not null,
+    version int not null,
+    content jsonb not null,
+    feature_id int,
+    tags_ids int[] not null default '{}',
+    is_active bool not null,
+    updated_at timestamp not null,
+    primary key (fk_banner_id, version),
+    foreign key (fk_banner_id) references banners(id)
+        on delete cascade on update restrict
+);
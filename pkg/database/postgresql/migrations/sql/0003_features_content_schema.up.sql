@@ -0,0 +1 @@
+alter table features add column if not exists content_schema jsonb;
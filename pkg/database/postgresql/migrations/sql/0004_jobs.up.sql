@@ -0,0 +1,12 @@
+create table if not exists jobs (
+    id bigserial not null,
+    feature_id int,
+    tag_id int,
+    status varchar(16) not null,
+    total int not null default 0,
+    processed int not null default 0,
+    error text not null default '',
+    created_at timestamp not null,
+    updated_at timestamp not null,
+    primary key (id)
+);
@@ -0,0 +1,11 @@
+create table if not exists sessions (
+    id bigserial not null,
+    fk_user_id int not null,
+    refresh_token varchar(64) not null,
+    expires_at timestamp not null,
+    created_at timestamp not null,
+    primary key (id),
+    constraint unique_refresh_token unique (refresh_token),
+    foreign key (fk_user_id) references users(id)
+        on delete cascade on update restrict
+);
@@ -0,0 +1,5 @@
+drop index if exists banners_active_until_idx;
+drop index if exists banners_active_from_idx;
+
+alter table banners drop column if exists active_until;
+alter table banners drop column if exists active_from;
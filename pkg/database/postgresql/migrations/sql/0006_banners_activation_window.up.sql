@@ -0,0 +1,5 @@
+alter table banners add column if not exists active_from timestamp;
+alter table banners add column if not exists active_until timestamp;
+
+create index if not exists banners_active_from_idx on banners (active_from) where active_from is not null;
+create index if not exists banners_active_until_idx on banners (active_until) where active_until is not null;
@@ -0,0 +1,14 @@
+create table if not exists banners_variants (
+    fk_feature_id int not null,
+    fk_tag_id int not null,
+    fk_banner_id int not null,
+    weight int not null,
+    primary key (fk_feature_id, fk_tag_id, fk_banner_id),
+    constraint positive_variant_weight check (weight > 0),
+    foreign key (fk_feature_id) references features(id)
+        on delete cascade on update restrict,
+    foreign key (fk_tag_id) references tags(id)
+        on delete cascade on update restrict,
+    foreign key (fk_banner_id) references banners(id)
+        on delete cascade on update restrict
+);
@@ -0,0 +1,9 @@
+create table if not exists banners_stats (
+    fk_banner_id int not null,
+    day date not null,
+    impressions bigint not null default 0,
+    clicks bigint not null default 0,
+    primary key (fk_banner_id, day),
+    foreign key (fk_banner_id) references banners(id)
+        on delete cascade on update restrict
+);
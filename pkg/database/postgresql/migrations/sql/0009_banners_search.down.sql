@@ -0,0 +1,5 @@
+drop index if exists banners_updated_at_idx;
+drop index if exists banners_created_at_idx;
+drop index if exists banners_search_vector_idx;
+
+alter table banners drop column if exists search_vector;
@@ -0,0 +1,7 @@
+alter table banners add column if not exists search_vector tsvector generated always as (
+    to_tsvector('simple', coalesce(content->>'title', '') || ' ' || coalesce(content->>'text', ''))
+) stored;
+
+create index if not exists banners_search_vector_idx on banners using gin (search_vector);
+create index if not exists banners_created_at_idx on banners (created_at, id);
+create index if not exists banners_updated_at_idx on banners (updated_at, id);
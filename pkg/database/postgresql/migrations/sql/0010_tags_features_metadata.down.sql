@@ -0,0 +1,9 @@
+alter table features drop constraint if exists unique_feature_name;
+alter table features drop column if exists owner_team;
+alter table features drop column if exists description;
+alter table features drop column if exists name;
+
+alter table tags drop constraint if exists unique_tag_name;
+alter table tags drop column if exists owner_team;
+alter table tags drop column if exists description;
+alter table tags drop column if exists name;
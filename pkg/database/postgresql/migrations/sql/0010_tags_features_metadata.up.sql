@@ -0,0 +1,9 @@
+alter table tags add column if not exists name varchar(255);
+alter table tags add column if not exists description text not null default '';
+alter table tags add column if not exists owner_team varchar(255) not null default '';
+alter table tags add constraint unique_tag_name unique (name);
+
+alter table features add column if not exists name varchar(255);
+alter table features add column if not exists description text not null default '';
+alter table features add column if not exists owner_team varchar(255) not null default '';
+alter table features add constraint unique_feature_name unique (name);
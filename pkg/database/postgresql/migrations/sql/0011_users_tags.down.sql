@@ -0,0 +1,12 @@
+alter table users add column if not exists fk_tag_id int;
+alter table users add constraint fk_tag
+    foreign key (fk_tag_id) references tags(id)
+        on delete restrict on update restrict;
+
+update users set fk_tag_id = first_tags.fk_tag_id
+from (
+    select distinct on (fk_user_id) fk_user_id, fk_tag_id from users_tags order by fk_user_id, priority
+) as first_tags
+where first_tags.fk_user_id = users.id;
+
+drop table if exists users_tags;
@@ -0,0 +1,20 @@
+create table if not exists users_tags (
+    fk_user_id int not null,
+    fk_tag_id int not null,
+    -- the lower priority the earlier tag is tried when banner for the user is chosen
+    priority int not null,
+    primary key (fk_user_id, fk_tag_id),
+    constraint unique_user_tag_priority unique (fk_user_id, priority),
+    foreign key (fk_user_id) references users(id)
+        on delete cascade on update restrict,
+    foreign key (fk_tag_id) references tags(id)
+        on delete cascade on update restrict
+);
+
+create index if not exists users_tags_tag_idx on users_tags (fk_tag_id, fk_user_id);
+
+insert into users_tags (fk_user_id, fk_tag_id, priority)
+select id, fk_tag_id, 0 from users where fk_tag_id is not null
+on conflict do nothing;
+
+alter table users drop column if exists fk_tag_id;
@@ -0,0 +1,2 @@
+alter table banners alter column active_until type timestamp using active_until at time zone 'UTC';
+alter table banners alter column active_from type timestamp using active_from at time zone 'UTC';
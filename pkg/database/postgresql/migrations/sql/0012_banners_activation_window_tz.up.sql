@@ -0,0 +1,4 @@
+-- activation window is compared with the current time and set from RFC3339 input with any offset,
+-- so it is stored as an instant. Offset of values stored before is unknown, they are taken as UTC.
+alter table banners alter column active_from type timestamptz using active_from at time zone 'UTC';
+alter table banners alter column active_until type timestamptz using active_until at time zone 'UTC';
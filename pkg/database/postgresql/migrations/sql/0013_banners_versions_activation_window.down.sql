@@ -0,0 +1,2 @@
+alter table banners_versions drop column if exists active_until;
+alter table banners_versions drop column if exists active_from;
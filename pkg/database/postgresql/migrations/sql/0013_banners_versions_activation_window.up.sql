@@ -0,0 +1,7 @@
+-- activation window is a part of banner version and is restored with it. Window of versions saved before is unknown,
+-- they get the current window of the banner, so activating them keeps it as it was before.
+alter table banners_versions add column if not exists active_from timestamptz;
+alter table banners_versions add column if not exists active_until timestamptz;
+
+update banners_versions set active_from = banners.active_from, active_until = banners.active_until
+from banners where banners.id = banners_versions.fk_banner_id;
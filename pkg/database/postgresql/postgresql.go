@@ -2,7 +2,6 @@ package postgresql
 
 import (
 	"avito-test2024-spring/internal/config"
-	"avito-test2024-spring/pkg/database/postgresql/dbscripts"
 	"avito-test2024-spring/pkg/logger"
 	"context"
 	"fmt"
@@ -29,13 +28,6 @@ func NewConnectionPool(cfg config.PostgreSQLConfig, logs *logger.Logs) *pgxpool.
 		return nil
 	}
 
-	_, err = pool.Exec(context.Background(), dbscripts.Create)
-	if err != nil {
-		logs.Logger.Error().Msg("error while creating DB scheme")
-		logs.Logger.Error().Msg(err.Error())
-		return nil
-	}
-
 	return pool
 }
 
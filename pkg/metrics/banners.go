@@ -0,0 +1,36 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	// UserBannerLoads counts user banner loads from DB made on cache miss or early refresh
+	UserBannerLoads = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "user_banner_db_loads_total",
+			Help: "Number of user banner loads from DB",
+		},
+	)
+
+	// UserBannerCoalesced counts requests which waited for a load started by another request instead of going to DB
+	UserBannerCoalesced = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "user_banner_coalesced_requests_total",
+			Help: "Number of user banner requests coalesced with concurrent DB load",
+		},
+	)
+
+	// UserBannerEarlyRefreshes counts cache entries refreshed before expiration
+	UserBannerEarlyRefreshes = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "user_banner_early_refreshes_total",
+			Help: "Number of user banner cache entries refreshed before expiration",
+		},
+	)
+)
+
+// Register adds metrics of banners serving to the default prometheus registry.
+func Register() {
+	prometheus.MustRegister(UserBannerLoads)
+	prometheus.MustRegister(UserBannerCoalesced)
+	prometheus.MustRegister(UserBannerEarlyRefreshes)
+}
@@ -0,0 +1,65 @@
+package pubsub
+
+import (
+	"avito-test2024-spring/internal/models"
+	"context"
+	"sync"
+)
+
+// Hub is an in-process pub/sub. Events are not queued for slow subscribers: when channel buffer of subscriber
+// is full, the event is dropped for it, so one stuck client can't block publishers.
+type Hub struct {
+	mu sync.Mutex
+
+	bufferSize  int
+	nextId      int
+	subscribers map[int]chan models.BannerEvent
+}
+
+func NewHub(bufferSize int) *Hub {
+	return &Hub{
+		bufferSize:  bufferSize,
+		subscribers: make(map[int]chan models.BannerEvent),
+	}
+}
+
+func (h *Hub) Publish(event models.BannerEvent) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, ch := range h.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+
+	return nil
+}
+
+func (h *Hub) Subscribe() (<-chan models.BannerEvent, func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	id := h.nextId
+	h.nextId++
+
+	ch := make(chan models.BannerEvent, h.bufferSize)
+	h.subscribers[id] = ch
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subscribers, id)
+			h.mu.Unlock()
+
+			close(ch)
+		})
+	}
+
+	return ch, unsubscribe
+}
+
+// Run does nothing, events of the hub are published in the same process.
+func (h *Hub) Run(ctx context.Context) {}
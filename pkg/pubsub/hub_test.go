@@ -0,0 +1,38 @@
+package pubsub
+
+import (
+	"avito-test2024-spring/internal/models"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestHub(t *testing.T) {
+	hub := NewHub(1)
+
+	first, unsubscribeFirst := hub.Subscribe()
+	second, unsubscribeSecond := hub.Subscribe()
+	defer unsubscribeSecond()
+
+	require.NoError(t, hub.Publish(models.BannerEvent{Type: models.BannerEventCreated, BannerId: 1}))
+	require.Equal(t, 1, (<-first).BannerId)
+	require.Equal(t, 1, (<-second).BannerId)
+
+	t.Run("Slow_Subscriber", func(t *testing.T) {
+		require.NoError(t, hub.Publish(models.BannerEvent{BannerId: 2}))
+		require.NoError(t, hub.Publish(models.BannerEvent{BannerId: 3}))
+
+		// the buffer holds one event, the next one is dropped instead of blocking publisher
+		require.Equal(t, 2, (<-second).BannerId)
+		require.Len(t, second, 0)
+		<-first
+	})
+
+	t.Run("Unsubscribe", func(t *testing.T) {
+		unsubscribeFirst()
+		unsubscribeFirst()
+
+		_, ok := <-first
+		require.False(t, ok)
+		require.NoError(t, hub.Publish(models.BannerEvent{BannerId: 4}))
+	})
+}
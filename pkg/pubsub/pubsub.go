@@ -0,0 +1,35 @@
+package pubsub
+
+import (
+	"avito-test2024-spring/internal/config"
+	"avito-test2024-spring/internal/models"
+	"context"
+	"fmt"
+)
+
+const (
+	BackendMemory = "memory"
+	BackendRedis  = "redis"
+)
+
+// PubSub delivers banner change events to subscribers. Events published by any replica reach subscribers
+// of all replicas when redis backend is used, memory backend delivers them only inside the process.
+type PubSub interface {
+	Publish(event models.BannerEvent) error
+	// Subscribe returns channel of events and function which unsubscribes and closes the channel.
+	Subscribe() (<-chan models.BannerEvent, func())
+	// Run receives events from other replicas until ctx is done.
+	Run(ctx context.Context)
+}
+
+// NewPubSub creates pub/sub backend selected in config.
+func NewPubSub(cfg config.EventsConfig, redisCfg config.RedisConfig) (PubSub, error) {
+	switch cfg.Backend {
+	case BackendMemory:
+		return NewHub(cfg.BufferSize), nil
+	case BackendRedis:
+		return NewRedisPubSub(redisCfg, cfg.Channel, NewHub(cfg.BufferSize)), nil
+	default:
+		return nil, fmt.Errorf("unknown events backend %q", cfg.Backend)
+	}
+}
@@ -0,0 +1,120 @@
+package pubsub
+
+import (
+	"avito-test2024-spring/internal/config"
+	"avito-test2024-spring/internal/models"
+	"context"
+	"encoding/json"
+	"github.com/gomodule/redigo/redis"
+	"time"
+)
+
+const defaultRetryInterval = time.Second
+
+// RedisPubSub publishes events to redis channel. Every replica subscribes to the channel and passes received
+// events to its hub, so events published by this replica reach local subscribers through redis as well.
+type RedisPubSub struct {
+	ConnPool *redis.Pool
+	Channel  string
+
+	RetryInterval time.Duration
+
+	hub *Hub
+}
+
+func NewRedisPubSub(cfg config.RedisConfig, channel string, hub *Hub) *RedisPubSub {
+	retryInterval := cfg.RetryInterval
+	if retryInterval <= 0 {
+		retryInterval = defaultRetryInterval
+	}
+
+	return &RedisPubSub{
+		ConnPool: &redis.Pool{
+			IdleTimeout: 5 * time.Second,
+			Dial: func() (redis.Conn, error) {
+				return redis.Dial("tcp", cfg.Host+":"+cfg.Port, redis.DialDatabase(cfg.DB))
+			},
+			TestOnBorrow: func(c redis.Conn, t time.Time) error {
+				_, err := c.Do("PING")
+				return err
+			},
+		},
+		Channel:       channel,
+		RetryInterval: retryInterval,
+		hub:           hub,
+	}
+}
+
+func (p *RedisPubSub) Publish(event models.BannerEvent) error {
+	message, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	conn := p.ConnPool.Get()
+	defer conn.Close()
+
+	_, err = conn.Do("PUBLISH", p.Channel, message)
+
+	return err
+}
+
+func (p *RedisPubSub) Subscribe() (<-chan models.BannerEvent, func()) {
+	return p.hub.Subscribe()
+}
+
+// Run subscribes to redis channel in background and resubscribes after connection errors until ctx is done.
+// Events published while the subscription is broken are lost.
+func (p *RedisPubSub) Run(ctx context.Context) {
+	go func() {
+		for {
+			p.receive(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(p.RetryInterval):
+			}
+		}
+	}()
+}
+
+// receive passes messages of the channel to the hub until connection fails or ctx is done.
+func (p *RedisPubSub) receive(ctx context.Context) {
+	conn := p.ConnPool.Get()
+	psc := redis.PubSubConn{Conn: conn}
+	defer psc.Close()
+
+	if err := psc.Subscribe(p.Channel); err != nil {
+		return
+	}
+
+	// unsubscribing unblocks Receive when ctx is done, it is allowed concurrently with Receive unlike Close
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			psc.Unsubscribe()
+		case <-done:
+		}
+	}()
+
+	for {
+		switch message := psc.Receive().(type) {
+		case redis.Message:
+			var event models.BannerEvent
+			if err := json.Unmarshal(message.Data, &event); err != nil {
+				continue
+			}
+
+			p.hub.Publish(event)
+		case redis.Subscription:
+			if message.Count == 0 {
+				return
+			}
+		case error:
+			return
+		}
+	}
+}